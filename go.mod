@@ -12,6 +12,7 @@ require (
 	github.com/choria-io/go-testutil v0.0.0-20191229210946-b9224ddf14db
 	github.com/choria-io/go-validator v1.1.1
 	github.com/fatih/color v1.7.0
+	github.com/ghodss/yaml v1.0.0
 	github.com/golang/mock v1.3.1
 	github.com/guptarohit/asciigraph v0.4.1
 	github.com/nats-io/nats.go v1.9.1
@@ -21,5 +22,6 @@ require (
 	github.com/sirupsen/logrus v1.4.2
 	github.com/tidwall/gjson v1.3.5
 	github.com/tidwall/pretty v1.0.0
+	github.com/xeipuuv/gojsonschema v1.2.0
 	go.uber.org/atomic v1.5.1
 )
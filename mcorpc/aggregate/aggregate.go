@@ -2,15 +2,33 @@ package aggregate
 
 import (
 	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
 )
 
 // Aggregator can summarize rpc reply data
+//
+// The internal state of an aggregator can be exported using State() and merged into
+// another aggregator of the same type using MergeState(), this allows partial results
+// from batches or different processes to be combined exactly
 type Aggregator interface {
 	ProcessValue(interface{}) error
 	ResultStrings() (map[string]string, error)
 	ResultFormattedStrings(format string) ([]string, error)
 	ResultJSON() ([]byte, error)
 	Type() string
+	Merge(other Aggregator) error
+	State() ([]byte, error)
+	MergeState(state []byte) error
+}
+
+// SenderAggregator is an Aggregator that can track which node sent each value
+type SenderAggregator interface {
+	Aggregator
+
+	ProcessSenderValue(sender string, v interface{}) error
 }
 
 // AggregatorByType retrieves an instance of an aggregator given its type like "summarize"
@@ -25,7 +43,161 @@ func AggregatorByType(t string, args []interface{}) (Aggregator, error) {
 	case "chart":
 		return NewChartAggregator(args)
 
+	case "stats", "percentile":
+		return NewStatsAggregator(args)
+
+	case "sum":
+		return NewSumAggregator(args)
+
+	case "min":
+		return NewMinAggregator(args)
+
+	case "max":
+		return NewMaxAggregator(args)
+
+	case "count":
+		return NewCountAggregator(args)
+
+	case "histogram":
+		return NewHistogramAggregator(args)
+
+	case "topn":
+		return NewTopNAggregator(args)
+
+	case "distinct":
+		return NewDistinctAggregator(args)
+
 	default:
 		return nil, fmt.Errorf("unknown aggregator '%s'", t)
 	}
 }
+
+// AggregatorFromState creates an aggregator of type t and restores a state previously produced by State()
+func AggregatorFromState(t string, args []interface{}, state []byte) (Aggregator, error) {
+	agg, err := AggregatorByType(t, args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = agg.MergeState(state)
+	if err != nil {
+		return nil, err
+	}
+
+	return agg, nil
+}
+
+// mergeAggregator merges the state of other into agg after ensuring they are of the same kind
+func mergeAggregator(agg Aggregator, other Aggregator) error {
+	if reflect.TypeOf(agg) != reflect.TypeOf(other) {
+		return fmt.Errorf("cannot merge '%s' aggregator into '%s' aggregator", other.Type(), agg.Type())
+	}
+
+	state, err := other.State()
+	if err != nil {
+		return err
+	}
+
+	return agg.MergeState(state)
+}
+
+// aggregatorOptions extracts the options hash from aggregator arguments, the
+// first argument is always the output name and the optional second one holds
+// options like those produced by `aggregate stats(:output, :format => "%s")`
+func aggregatorOptions(args []interface{}) map[string]interface{} {
+	if len(args) < 2 {
+		return map[string]interface{}{}
+	}
+
+	opts, ok := args[1].(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}
+	}
+
+	return opts
+}
+
+// aggregatorFormat retrieves the format option from aggregator arguments
+func aggregatorFormat(args []interface{}) string {
+	format, ok := aggregatorOptions(args)["format"].(string)
+	if !ok {
+		return ""
+	}
+
+	return format
+}
+
+// numericValue converts the numeric types and numeric strings found in replies to float64,
+// values like "Inf" and "NaN" are rejected as they cannot be bucketed, charted or encoded
+func numericValue(v interface{}) (float64, bool) {
+	var f float64
+
+	switch val := v.(type) {
+	case int:
+		f = float64(val)
+
+	case int64:
+		f = float64(val)
+
+	case float64:
+		f = val
+
+	case string:
+		var err error
+
+		f, err = strconv.ParseFloat(val, 64)
+		if err != nil {
+			return 0, false
+		}
+
+	default:
+		return 0, false
+	}
+
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, false
+	}
+
+	return f, true
+}
+
+// formatNumber formats f without trailing zeros so that integer results are shown as integers
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// sprintfNumber formats f using a user supplied format, formats written for integers like
+// "Updated: %d" receive f rounded to an int64 rather than rendering as %!d(float64=...)
+func sprintfNumber(format string, f float64) string {
+	if integerVerb(format) {
+		return fmt.Sprintf(format, int64(math.Round(f)))
+	}
+
+	return fmt.Sprintf(format, f)
+}
+
+// integerVerb determines if the first formatting verb in format only accepts integers
+func integerVerb(format string) bool {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+
+		i++
+		for i < len(format) && strings.ContainsRune("+-# 0123456789.*[]", rune(format[i])) {
+			i++
+		}
+
+		if i >= len(format) {
+			return false
+		}
+
+		if format[i] == '%' {
+			continue
+		}
+
+		return strings.ContainsRune("bcdoOUxX", rune(format[i]))
+	}
+
+	return false
+}
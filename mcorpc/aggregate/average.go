@@ -139,3 +139,38 @@ func (a *AverageAggregator) processInt(v interface{}) bool {
 
 	return true
 }
+
+// Merge merges the state of another AverageAggregator into this one
+func (a *AverageAggregator) Merge(other Aggregator) error {
+	return mergeAggregator(a, other)
+}
+
+type averageState struct {
+	Sum   float64 `json:"sum"`
+	Count int     `json:"count"`
+}
+
+// State returns the internal state of the aggregator in JSON format
+func (a *AverageAggregator) State() ([]byte, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	return json.Marshal(averageState{Sum: a.sum, Count: a.count})
+}
+
+// MergeState merges a state previously produced by State() into this aggregator
+func (a *AverageAggregator) MergeState(state []byte) error {
+	s := averageState{}
+	err := json.Unmarshal(state, &s)
+	if err != nil {
+		return fmt.Errorf("invalid average aggregator state: %s", err)
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	a.sum = a.sum + s.Sum
+	a.count = a.count + s.Count
+
+	return nil
+}
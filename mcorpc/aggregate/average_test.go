@@ -41,4 +41,27 @@ var _ = Describe("AverageAggregator", func() {
 			Expect(jresults).To(MatchJSON("{\"average\":25.775}"))
 		})
 	})
+
+	Describe("Merge", func() {
+		It("Should merge other averages", func() {
+			other, err := NewAverageAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(other.ProcessValue(2)).ToNot(HaveOccurred())
+			Expect(other.ProcessValue(6)).ToNot(HaveOccurred())
+
+			Expect(agg.Merge(other)).ToNot(HaveOccurred())
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{"Average": "3.000000"}))
+		})
+
+		It("Should not merge other types", func() {
+			other, err := NewSumAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(agg.Merge(other)).To(MatchError("cannot merge 'sum' aggregator into 'average' aggregator"))
+		})
+	})
 })
@@ -3,23 +3,93 @@ package aggregate
 import (
 	"encoding/json"
 	"fmt"
-	"strconv"
+	"math"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/guptarohit/asciigraph"
 )
 
-// ChartAggregator tracks seen values and produce a sparkline with values bucketed in groups of 50
+// ChartAggregator tracks seen values and produce a chart or a compact sparkline of them
 type ChartAggregator struct {
-	items []float64
+	items   []float64
+	senders []string
+
+	height  int
+	width   int
+	caption string
+	sortBy  string
+	mode    string
 
 	sync.Mutex
 }
 
+type chartPoint struct {
+	Sender string  `json:"sender,omitempty"`
+	Value  float64 `json:"value"`
+}
+
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
 // NewChartAggregator creates a new ChartAggregator with the specific options
+//
+// Supported options are "height" and "width" of the chart, a "caption" to show below it,
+// "sort" which can be "received", "ascending", "descending" or "sender" and "mode" which
+// can be "chart" or "sparkline" for a compact single line chart limited to width characters
 func NewChartAggregator(args []interface{}) (*ChartAggregator, error) {
 	agg := &ChartAggregator{
-		items: []float64{},
+		items:   []float64{},
+		senders: []string{},
+		height:  15,
+		width:   60,
+		sortBy:  "received",
+		mode:    "chart",
+	}
+
+	opts := aggregatorOptions(args)
+
+	for _, opt := range []string{"height", "width"} {
+		v, ok := opts[opt]
+		if !ok {
+			continue
+		}
+
+		i, ok := numericValue(v)
+		if !ok || i < 1 {
+			return nil, fmt.Errorf("%s should be a positive number", opt)
+		}
+
+		if opt == "height" {
+			agg.height = int(i)
+		} else {
+			agg.width = int(i)
+		}
+	}
+
+	caption, ok := opts["caption"]
+	if ok {
+		agg.caption = fmt.Sprintf("%v", caption)
+	}
+
+	sortBy, ok := opts["sort"]
+	if ok {
+		switch sortBy {
+		case "received", "ascending", "descending", "sender":
+			agg.sortBy = sortBy.(string)
+		default:
+			return nil, fmt.Errorf("invalid sort '%v', valid options are received, ascending, descending and sender", sortBy)
+		}
+	}
+
+	mode, ok := opts["mode"]
+	if ok {
+		switch mode {
+		case "chart", "sparkline":
+			agg.mode = mode.(string)
+		default:
+			return nil, fmt.Errorf("invalid mode '%v', valid options are chart and sparkline", mode)
+		}
 	}
 
 	return agg, nil
@@ -32,37 +102,35 @@ func (s *ChartAggregator) Type() string {
 
 // ProcessValue processes and tracks the specific value
 func (s *ChartAggregator) ProcessValue(v interface{}) error {
+	return s.ProcessSenderValue("", v)
+}
+
+// ProcessSenderValue processes and tracks the specific value received from sender
+func (s *ChartAggregator) ProcessSenderValue(sender string, v interface{}) error {
 	s.Lock()
 	defer s.Unlock()
 
-	if s.processInt(v) {
-		return nil
-	}
-
-	if s.processInt64(v) {
-		return nil
-	}
-
-	if s.processFloat(v) {
-		return nil
+	f, ok := numericValue(v)
+	if !ok {
+		return fmt.Errorf("unsupported data type for chart aggregator")
 	}
 
-	if s.processString(v) {
-		return nil
-	}
+	s.items = append(s.items, f)
+	s.senders = append(s.senders, sender)
 
-	return fmt.Errorf("unsupported data type for chart aggregator")
+	return nil
 }
 
-// ResultJSON return the sparkline as a JSON document with the key "chart"
+// ResultJSON return the chart as a JSON document with the key "chart" and the values with their senders in "series"
 func (s *ChartAggregator) ResultJSON() ([]byte, error) {
 	s.Lock()
 	defer s.Unlock()
 
 	line := s.chart()
 
-	return json.Marshal(map[string]string{
-		"chart": line,
+	return json.Marshal(map[string]interface{}{
+		"chart":  line,
+		"series": s.series(),
 	})
 }
 
@@ -90,55 +158,131 @@ func (s *ChartAggregator) ResultFormattedStrings(format string) ([]string, error
 	return []string{fmt.Sprintf(format, line)}, nil
 }
 
-func (s *ChartAggregator) processString(v interface{}) bool {
-	str, ok := v.(string)
-	if !ok {
-		return false
+// series is the values and senders in the configured sort order, must be called with the lock held
+func (s *ChartAggregator) series() []chartPoint {
+	series := make([]chartPoint, len(s.items))
+	for i, v := range s.items {
+		series[i] = chartPoint{Sender: s.senders[i], Value: v}
 	}
 
-	f, err := strconv.ParseFloat(str, 64)
-	if err != nil {
-		return false
+	switch s.sortBy {
+	case "ascending":
+		sort.SliceStable(series, func(i, j int) bool { return series[i].Value < series[j].Value })
+	case "descending":
+		sort.SliceStable(series, func(i, j int) bool { return series[i].Value > series[j].Value })
+	case "sender":
+		sort.SliceStable(series, func(i, j int) bool { return series[i].Sender < series[j].Sender })
 	}
 
-	s.items = append(s.items, f)
+	return series
+}
 
-	return true
+func (s *ChartAggregator) values() []float64 {
+	series := s.series()
+	values := make([]float64, len(series))
+	for i, p := range series {
+		values[i] = p.Value
+	}
+
+	return values
 }
 
-func (s *ChartAggregator) processFloat(v interface{}) bool {
-	f, ok := v.(float64)
-	if !ok {
-		return false
+func (s *ChartAggregator) chart() string {
+	if s.mode == "sparkline" {
+		return s.sparkline()
 	}
 
-	s.items = append(s.items, f)
+	opts := []asciigraph.Option{asciigraph.Height(s.height), asciigraph.Width(s.width), asciigraph.Offset(5)}
+	if s.caption != "" {
+		opts = append(opts, asciigraph.Caption(s.caption))
+	}
 
-	return true
+	return asciigraph.Plot(s.values(), opts...)
 }
 
-func (s *ChartAggregator) processInt(v interface{}) bool {
-	i, ok := v.(int)
-	if !ok {
-		return false
+func (s *ChartAggregator) sparkline() string {
+	values := s.values()
+	if len(values) == 0 {
+		return ""
 	}
 
-	s.items = append(s.items, float64(i))
+	// when there are more values than room average them into width groups
+	if len(values) > s.width {
+		grouped := make([]float64, s.width)
+		for i := range grouped {
+			start := i * len(values) / s.width
+			end := (i + 1) * len(values) / s.width
 
-	return true
-}
+			sum := 0.0
+			for _, v := range values[start:end] {
+				sum += v
+			}
 
-func (s *ChartAggregator) processInt64(v interface{}) bool {
-	i, ok := v.(int64)
-	if !ok {
-		return false
+			grouped[i] = sum / float64(end-start)
+		}
+
+		values = grouped
+	}
+
+	min := values[0]
+	max := values[0]
+	for _, v := range values {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
 	}
 
-	s.items = append(s.items, float64(i))
+	var line strings.Builder
+	for _, v := range values {
+		idx := 0
+		if max > min {
+			idx = int(math.Round((v - min) / (max - min) * float64(len(sparkTicks)-1)))
+		}
 
-	return true
+		line.WriteRune(sparkTicks[idx])
+	}
+
+	if s.caption != "" {
+		return fmt.Sprintf("%s %s", line.String(), s.caption)
+	}
+
+	return line.String()
 }
 
-func (s *ChartAggregator) chart() string {
-	return asciigraph.Plot(s.items, asciigraph.Height(15), asciigraph.Width(60), asciigraph.Offset(5))
+// Merge merges the state of another ChartAggregator into this one
+func (s *ChartAggregator) Merge(other Aggregator) error {
+	return mergeAggregator(s, other)
+}
+
+type chartState struct {
+	Items   []float64 `json:"items"`
+	Senders []string  `json:"senders"`
+}
+
+// State returns the internal state of the aggregator in JSON format
+func (s *ChartAggregator) State() ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return json.Marshal(chartState{Items: s.items, Senders: s.senders})
+}
+
+// MergeState merges a state previously produced by State() into this aggregator, merged values are added after those already seen
+func (s *ChartAggregator) MergeState(state []byte) error {
+	cs := chartState{}
+	err := json.Unmarshal(state, &cs)
+	if err != nil {
+		return fmt.Errorf("invalid chart aggregator state: %s", err)
+	}
+
+	if len(cs.Senders) != len(cs.Items) {
+		cs.Senders = make([]string, len(cs.Items))
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.items = append(s.items, cs.Items...)
+	s.senders = append(s.senders, cs.Senders...)
+
+	return nil
 }
@@ -54,11 +54,16 @@ var _ = Describe("ChartAggregator", func() {
 				results, err := agg.ResultJSON()
 				Expect(err).ToNot(HaveOccurred())
 
-				jexpected, err := json.Marshal(map[string]string{
+				jexpected, err := json.Marshal(map[string]interface{}{
 					"chart": expected,
+					"series": []map[string]interface{}{
+						{"value": 1},
+						{"value": 7},
+						{"value": 50},
+					},
 				})
 				Expect(err).ToNot(HaveOccurred())
-				Expect(results).To(Equal(jexpected))
+				Expect(results).To(MatchJSON(jexpected))
 			})
 		})
 
@@ -70,4 +75,109 @@ var _ = Describe("ChartAggregator", func() {
 			})
 		})
 	})
+
+	Describe("Merge", func() {
+		It("Should append the other values", func() {
+			other, err := NewChartAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(other.ProcessValue(2)).ToNot(HaveOccurred())
+
+			Expect(agg.Merge(other)).ToNot(HaveOccurred())
+			Expect(agg.items).To(Equal([]float64{1, 2}))
+		})
+	})
+
+	Describe("Options", func() {
+		BeforeEach(func() {
+			agg, err = NewChartAggregator([]interface{}{"x", map[string]interface{}{"sort": "sender", "height": 5.0, "width": 20.0, "caption": "Load"}})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(agg.ProcessSenderValue("node3", 3)).ToNot(HaveOccurred())
+			Expect(agg.ProcessSenderValue("node1", 1)).ToNot(HaveOccurred())
+			Expect(agg.ProcessSenderValue("node2", 2)).ToNot(HaveOccurred())
+		})
+
+		It("Should validate options", func() {
+			_, err = NewChartAggregator([]interface{}{"x", map[string]interface{}{"sort": "random"}})
+			Expect(err).To(MatchError("invalid sort 'random', valid options are received, ascending, descending and sender"))
+
+			_, err = NewChartAggregator([]interface{}{"x", map[string]interface{}{"mode": "pie"}})
+			Expect(err).To(MatchError("invalid mode 'pie', valid options are chart and sparkline"))
+
+			_, err = NewChartAggregator([]interface{}{"x", map[string]interface{}{"height": 0.0}})
+			Expect(err).To(MatchError("height should be a positive number"))
+		})
+
+		It("Should draw the chart using the options", func() {
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results["Chart"]).To(Equal(asciigraph.Plot([]float64{1, 2, 3}, asciigraph.Height(5), asciigraph.Width(20), asciigraph.Offset(5), asciigraph.Caption("Load"))))
+		})
+
+		It("Should include the senders in the JSON series", func() {
+			results, err := agg.ResultJSON()
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed := map[string]interface{}{}
+			Expect(json.Unmarshal(results, &parsed)).ToNot(HaveOccurred())
+			Expect(parsed["series"]).To(Equal([]interface{}{
+				map[string]interface{}{"sender": "node1", "value": 1.0},
+				map[string]interface{}{"sender": "node2", "value": 2.0},
+				map[string]interface{}{"sender": "node3", "value": 3.0},
+			}))
+		})
+
+		It("Should merge senders", func() {
+			other, err := NewChartAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(other.ProcessSenderValue("node0", 0)).ToNot(HaveOccurred())
+
+			Expect(agg.Merge(other)).ToNot(HaveOccurred())
+			Expect(agg.senders).To(Equal([]string{"node3", "node1", "node2", "node0"}))
+		})
+	})
+
+	Describe("Sparkline", func() {
+		It("Should produce a sparkline", func() {
+			agg, err = NewChartAggregator([]interface{}{"x", map[string]interface{}{"mode": "sparkline"}})
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, v := range []int{0, 1, 2, 3, 4, 5, 6, 7} {
+				Expect(agg.ProcessValue(v)).ToNot(HaveOccurred())
+			}
+
+			results, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal([]string{"▁▂▃▄▅▆▇█"}))
+		})
+
+		It("Should group values wider than the width", func() {
+			agg, err = NewChartAggregator([]interface{}{"x", map[string]interface{}{"mode": "sparkline", "width": 2.0, "sort": "ascending"}})
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, v := range []int{10, 0, 10, 0} {
+				Expect(agg.ProcessValue(v)).ToNot(HaveOccurred())
+			}
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results["Chart"]).To(Equal("▁█"))
+		})
+
+		It("Should ignore non-finite values", func() {
+			agg, err = NewChartAggregator([]interface{}{"x", map[string]interface{}{"mode": "sparkline"}})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(agg.ProcessValue(1.0)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("Inf")).To(HaveOccurred())
+			Expect(agg.ProcessValue("NaN")).To(HaveOccurred())
+			Expect(agg.ProcessValue(2.0)).ToNot(HaveOccurred())
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results["Chart"]).To(Equal("▁█"))
+		})
+	})
 })
@@ -0,0 +1,110 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// CountAggregator counts how many values were seen
+type CountAggregator struct {
+	count  int
+	format string
+
+	sync.Mutex
+}
+
+// NewCountAggregator creates a new CountAggregator with the specific options supplied
+func NewCountAggregator(args []interface{}) (*CountAggregator, error) {
+	agg := &CountAggregator{
+		format: aggregatorFormat(args),
+	}
+
+	return agg, nil
+}
+
+// Type is the type of Aggregator
+func (a *CountAggregator) Type() string {
+	return "count"
+}
+
+// ProcessValue processes and tracks the specific value, any non nil value is counted
+func (a *CountAggregator) ProcessValue(v interface{}) error {
+	a.Lock()
+	defer a.Unlock()
+
+	if v == nil {
+		return nil
+	}
+
+	a.count++
+
+	return nil
+}
+
+// ResultJSON return the results in JSON format preserving types
+func (a *CountAggregator) ResultJSON() ([]byte, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	return json.Marshal(map[string]int{
+		"count": a.count,
+	})
+}
+
+// ResultStrings returns a map of results in string format
+func (a *CountAggregator) ResultStrings() (map[string]string, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	return map[string]string{"Count": fmt.Sprintf("%d", a.count)}, nil
+}
+
+// ResultFormattedStrings return the results in a formatted way, if no format is given a calculated value is used
+func (a *CountAggregator) ResultFormattedStrings(format string) ([]string, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if format == "" {
+		format = a.format
+	}
+
+	if format == "" {
+		format = "Count: %d"
+	}
+
+	return []string{fmt.Sprintf(format, a.count)}, nil
+}
+
+// Merge merges the state of another CountAggregator into this one
+func (a *CountAggregator) Merge(other Aggregator) error {
+	return mergeAggregator(a, other)
+}
+
+type countState struct {
+	Count int `json:"count"`
+}
+
+// State returns the internal state of the aggregator in JSON format
+func (a *CountAggregator) State() ([]byte, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	return json.Marshal(countState{Count: a.count})
+}
+
+// MergeState merges a state previously produced by State() into this aggregator
+func (a *CountAggregator) MergeState(state []byte) error {
+	s := countState{}
+	err := json.Unmarshal(state, &s)
+	if err != nil {
+		return fmt.Errorf("invalid count aggregator state: %s", err)
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	a.count = a.count + s.Count
+
+	return nil
+}
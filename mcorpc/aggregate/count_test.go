@@ -0,0 +1,55 @@
+package aggregate
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CountAggregator", func() {
+	var (
+		err error
+		agg *CountAggregator
+	)
+
+	BeforeEach(func() {
+		agg, err = NewCountAggregator([]interface{}{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("ProcessValue", func() {
+		It("Should process various values", func() {
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(1.5)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("a")).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(nil)).ToNot(HaveOccurred())
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{
+				"Count": "3",
+			}))
+
+			fresults, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fresults).To(Equal([]string{
+				"Count: 3",
+			}))
+
+			jresults, err := agg.ResultJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jresults).To(MatchJSON(`{"count":3}`))
+		})
+	})
+
+	Describe("Merge", func() {
+		It("Should add the other counts", func() {
+			state, err := agg.State()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(agg.MergeState([]byte(`{"count":10}`))).ToNot(HaveOccurred())
+			Expect(agg.MergeState(state)).ToNot(HaveOccurred())
+			Expect(agg.count).To(Equal(11))
+		})
+	})
+})
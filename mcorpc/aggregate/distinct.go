@@ -0,0 +1,290 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+)
+
+// DistinctAggregator counts how many distinct values were seen
+//
+// Values are counted exactly until a limit is reached after which a HyperLogLog
+// sketch is used to estimate the cardinality in bounded memory
+type DistinctAggregator struct {
+	exact      map[string]struct{}
+	sketch     *hyperLogLog
+	exactLimit int
+	precision  uint8
+	format     string
+
+	sync.Mutex
+}
+
+// NewDistinctAggregator creates a new DistinctAggregator with the specific options supplied
+//
+// Supported options are "exact_limit", the number of distinct values to count exactly
+// that defaults to 10000, "precision", the HyperLogLog precision between 4 and 16 that
+// defaults to 14 and "format" which is used for formatted results and receives the count
+func NewDistinctAggregator(args []interface{}) (*DistinctAggregator, error) {
+	agg := &DistinctAggregator{
+		exact:      make(map[string]struct{}),
+		exactLimit: 10000,
+		precision:  14,
+		format:     aggregatorFormat(args),
+	}
+
+	opts := aggregatorOptions(args)
+
+	l, ok := opts["exact_limit"]
+	if ok {
+		limit, ok := numericValue(l)
+		if !ok || limit < 0 {
+			return nil, fmt.Errorf("exact_limit should be a positive number")
+		}
+
+		agg.exactLimit = int(limit)
+	}
+
+	p, ok := opts["precision"]
+	if ok {
+		precision, ok := numericValue(p)
+		if !ok || precision < 4 || precision > 16 {
+			return nil, fmt.Errorf("precision should be a number between 4 and 16")
+		}
+
+		agg.precision = uint8(precision)
+	}
+
+	return agg, nil
+}
+
+// Type is the type of Aggregator
+func (d *DistinctAggregator) Type() string {
+	return "distinct"
+}
+
+// ProcessValue processes and tracks a specified value
+func (d *DistinctAggregator) ProcessValue(v interface{}) error {
+	d.Lock()
+	defer d.Unlock()
+
+	d.add(fmt.Sprintf("%v", v))
+
+	return nil
+}
+
+// add tracks val switching to the sketch when the exact limit is passed, must be called with the lock held
+func (d *DistinctAggregator) add(val string) {
+	if d.sketch != nil {
+		d.sketch.add(val)
+		return
+	}
+
+	d.exact[val] = struct{}{}
+
+	if len(d.exact) > d.exactLimit {
+		d.sketch = newHyperLogLog(d.precision)
+
+		for k := range d.exact {
+			d.sketch.add(k)
+		}
+
+		d.exact = nil
+	}
+}
+
+// ResultStrings returns a map of results in string format
+func (d *DistinctAggregator) ResultStrings() (map[string]string, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	return map[string]string{"Distinct": fmt.Sprintf("%d", d.cardinality())}, nil
+}
+
+// ResultJSON return the results in JSON format, estimated is true when the count is an estimate
+func (d *DistinctAggregator) ResultJSON() ([]byte, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	return json.Marshal(map[string]interface{}{
+		"distinct":  d.cardinality(),
+		"estimated": d.sketch != nil,
+	})
+}
+
+// ResultFormattedStrings return the results in a formatted way, if no format is given a calculated value is used
+func (d *DistinctAggregator) ResultFormattedStrings(format string) ([]string, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	if format == "" {
+		format = d.format
+	}
+
+	if format == "" {
+		format = "Distinct: %d"
+		if d.sketch != nil {
+			format = "Distinct: ~%d"
+		}
+	}
+
+	return []string{fmt.Sprintf(format, d.cardinality())}, nil
+}
+
+func (d *DistinctAggregator) cardinality() uint64 {
+	if d.sketch != nil {
+		return d.sketch.estimate()
+	}
+
+	return uint64(len(d.exact))
+}
+
+// hyperLogLog is a minimal HyperLogLog cardinality estimator using 64 bit hashes
+type hyperLogLog struct {
+	p         uint8
+	registers []uint8
+}
+
+func newHyperLogLog(p uint8) *hyperLogLog {
+	return &hyperLogLog{
+		p:         p,
+		registers: make([]uint8, 1<<p),
+	}
+}
+
+func (h *hyperLogLog) add(v string) {
+	x := hashString(v)
+	idx := x >> (64 - h.p)
+
+	// the remaining bits with a sentinel bit so the rank is bounded
+	w := x<<h.p | 1<<(h.p-1)
+	rank := uint8(bits.LeadingZeros64(w)) + 1
+
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+// merge combines other into h, both need to have the same precision
+func (h *hyperLogLog) merge(other *hyperLogLog) error {
+	if h.p != other.p {
+		return fmt.Errorf("cannot merge HyperLogLog sketches with precision %d and %d", other.p, h.p)
+	}
+
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+
+	return nil
+}
+
+func (h *hyperLogLog) estimate() uint64 {
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+
+	for _, r := range h.registers {
+		sum += math.Pow(2, -float64(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	est := alpha * m * m / sum
+
+	// small range correction using linear counting
+	if est <= 2.5*m && zeros > 0 {
+		est = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(math.Round(est))
+}
+
+// hashString hashes v using FNV-1a with a final avalanche step to spread the bits evenly
+func hashString(v string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(v))
+	x := h.Sum64()
+
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	return x
+}
+
+// Merge merges the state of another DistinctAggregator into this one
+func (d *DistinctAggregator) Merge(other Aggregator) error {
+	return mergeAggregator(d, other)
+}
+
+type distinctState struct {
+	Exact     []string `json:"exact,omitempty"`
+	Precision uint8    `json:"precision,omitempty"`
+	Registers []uint8  `json:"registers,omitempty"`
+}
+
+// State returns the internal state of the aggregator in JSON format
+func (d *DistinctAggregator) State() ([]byte, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	s := distinctState{}
+
+	if d.sketch != nil {
+		s.Precision = d.sketch.p
+		s.Registers = d.sketch.registers
+	} else {
+		for k := range d.exact {
+			s.Exact = append(s.Exact, k)
+		}
+		sort.Strings(s.Exact)
+	}
+
+	return json.Marshal(s)
+}
+
+// MergeState merges a state previously produced by State() into this aggregator
+func (d *DistinctAggregator) MergeState(state []byte) error {
+	s := distinctState{}
+	err := json.Unmarshal(state, &s)
+	if err != nil {
+		return fmt.Errorf("invalid distinct aggregator state: %s", err)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	if len(s.Registers) > 0 {
+		if len(s.Registers) != 1<<s.Precision {
+			return fmt.Errorf("invalid distinct aggregator state: expected %d registers for precision %d", 1<<s.Precision, s.Precision)
+		}
+
+		if d.sketch == nil {
+			d.sketch = newHyperLogLog(s.Precision)
+
+			for k := range d.exact {
+				d.sketch.add(k)
+			}
+
+			d.exact = nil
+		}
+
+		return d.sketch.merge(&hyperLogLog{p: s.Precision, registers: s.Registers})
+	}
+
+	for _, k := range s.Exact {
+		d.add(k)
+	}
+
+	return nil
+}
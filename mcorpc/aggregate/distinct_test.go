@@ -0,0 +1,96 @@
+package aggregate
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DistinctAggregator", func() {
+	var (
+		err error
+		agg *DistinctAggregator
+	)
+
+	BeforeEach(func() {
+		agg, err = NewDistinctAggregator([]interface{}{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("NewDistinctAggregator", func() {
+		It("Should detect invalid precision", func() {
+			_, err = NewDistinctAggregator([]interface{}{"x", map[string]interface{}{"precision": 20.0}})
+			Expect(err).To(MatchError("precision should be a number between 4 and 16"))
+		})
+	})
+
+	Describe("ProcessValue", func() {
+		It("Should count exactly below the limit", func() {
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("1")).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("a")).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("b")).ToNot(HaveOccurred())
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{"Distinct": "3"}))
+
+			fresults, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fresults).To(Equal([]string{"Distinct: 3"}))
+
+			jresults, err := agg.ResultJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jresults).To(MatchJSON(`{"distinct":3, "estimated":false}`))
+		})
+
+		It("Should estimate above the limit", func() {
+			agg, err = NewDistinctAggregator([]interface{}{"x", map[string]interface{}{"exact_limit": 100.0}})
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 0; i < 50000; i++ {
+				Expect(agg.ProcessValue(fmt.Sprintf("node%d.example.net", i%20000))).ToNot(HaveOccurred())
+			}
+
+			Expect(agg.exact).To(BeNil())
+			Expect(agg.sketch).ToNot(BeNil())
+			Expect(agg.cardinality()).To(BeNumerically("~", 20000, 400))
+
+			fresults, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fresults[0]).To(HavePrefix("Distinct: ~"))
+		})
+	})
+
+	Describe("Merge", func() {
+		It("Should merge exact counts", func() {
+			other, err := NewDistinctAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(agg.ProcessValue("a")).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("b")).ToNot(HaveOccurred())
+			Expect(other.ProcessValue("b")).ToNot(HaveOccurred())
+			Expect(other.ProcessValue("c")).ToNot(HaveOccurred())
+
+			Expect(agg.Merge(other)).ToNot(HaveOccurred())
+			Expect(agg.cardinality()).To(Equal(uint64(3)))
+			Expect(agg.sketch).To(BeNil())
+		})
+
+		It("Should merge sketches", func() {
+			agg, err = NewDistinctAggregator([]interface{}{"x", map[string]interface{}{"exact_limit": 10.0}})
+			Expect(err).ToNot(HaveOccurred())
+			other, err := NewDistinctAggregator([]interface{}{"x", map[string]interface{}{"exact_limit": 10.0}})
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 0; i < 5000; i++ {
+				Expect(agg.ProcessValue(fmt.Sprintf("node%d", i))).ToNot(HaveOccurred())
+				Expect(other.ProcessValue(fmt.Sprintf("node%d", i+2500))).ToNot(HaveOccurred())
+			}
+
+			Expect(agg.Merge(other)).ToNot(HaveOccurred())
+			Expect(agg.cardinality()).To(BeNumerically("~", 7500, 150))
+		})
+	})
+})
@@ -0,0 +1,140 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// extremeAggregator tracks the seen value that compares best using better, it
+// is the shared implementation of the min and max aggregators
+type extremeAggregator struct {
+	name   string
+	label  string
+	better func(f float64, current float64) bool
+
+	value  float64
+	seen   bool
+	format string
+
+	sync.Mutex
+}
+
+func newExtremeAggregator(name string, label string, better func(float64, float64) bool, args []interface{}) extremeAggregator {
+	return extremeAggregator{
+		name:   name,
+		label:  label,
+		better: better,
+		format: aggregatorFormat(args),
+	}
+}
+
+// Type is the type of Aggregator
+func (a *extremeAggregator) Type() string {
+	return a.name
+}
+
+// ProcessValue processes and tracks the specific value
+func (a *extremeAggregator) ProcessValue(v interface{}) error {
+	f, ok := numericValue(v)
+	if !ok {
+		return fmt.Errorf("unsupported data type for %s aggregator", a.name)
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	a.track(f)
+
+	return nil
+}
+
+func (a *extremeAggregator) track(f float64) {
+	if !a.seen || a.better(f, a.value) {
+		a.value = f
+		a.seen = true
+	}
+}
+
+// ResultJSON return the results in JSON format preserving types, the value is null when no values were seen
+func (a *extremeAggregator) ResultJSON() ([]byte, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if !a.seen {
+		return json.Marshal(map[string]interface{}{
+			a.name: nil,
+		})
+	}
+
+	return json.Marshal(map[string]float64{
+		a.name: a.value,
+	})
+}
+
+// ResultStrings returns a map of results in string format
+func (a *extremeAggregator) ResultStrings() (map[string]string, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if !a.seen {
+		return map[string]string{}, nil
+	}
+
+	return map[string]string{a.label: formatNumber(a.value)}, nil
+}
+
+// ResultFormattedStrings return the results in a formatted way, if no format is given a calculated value is used
+func (a *extremeAggregator) ResultFormattedStrings(format string) ([]string, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if !a.seen {
+		return []string{}, nil
+	}
+
+	if format == "" {
+		format = a.format
+	}
+
+	if format == "" {
+		return []string{fmt.Sprintf("%s: %s", a.label, formatNumber(a.value))}, nil
+	}
+
+	return []string{sprintfNumber(format, a.value)}, nil
+}
+
+// State returns the internal state of the aggregator in JSON format
+func (a *extremeAggregator) State() ([]byte, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	s := map[string]*float64{a.name: nil}
+	if a.seen {
+		v := a.value
+		s[a.name] = &v
+	}
+
+	return json.Marshal(s)
+}
+
+// MergeState merges a state previously produced by State() into this aggregator
+func (a *extremeAggregator) MergeState(state []byte) error {
+	s := map[string]*float64{}
+	err := json.Unmarshal(state, &s)
+	if err != nil {
+		return fmt.Errorf("invalid %s aggregator state: %s", a.name, err)
+	}
+
+	v := s[a.name]
+	if v == nil {
+		return nil
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	a.track(*v)
+
+	return nil
+}
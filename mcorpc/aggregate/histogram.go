@@ -0,0 +1,358 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// HistogramAggregator tracks seen values and groups them into buckets
+//
+// Buckets are either a list of explicit bucket edges or a count of buckets that
+// will be spread evenly between the smallest and largest values seen
+type HistogramAggregator struct {
+	items  []float64
+	edges  []float64
+	count  int
+	width  int
+	format string
+
+	sync.Mutex
+}
+
+type histogramBucket struct {
+	Low   *float64 `json:"low"`
+	High  *float64 `json:"high"`
+	Count int      `json:"count"`
+}
+
+// NewHistogramAggregator creates a new HistogramAggregator with the specific options supplied
+//
+// Supported options are "buckets", either a number of buckets or a list of bucket edges and
+// defaults to 10 buckets, "width", the maximum width of the bars and "format" which is used
+// for formatted results and receives the bucket name and count
+func NewHistogramAggregator(args []interface{}) (*HistogramAggregator, error) {
+	agg := &HistogramAggregator{
+		items:  []float64{},
+		count:  10,
+		width:  50,
+		format: aggregatorFormat(args),
+	}
+
+	opts := aggregatorOptions(args)
+
+	switch buckets := opts["buckets"].(type) {
+	case nil:
+
+	case []interface{}:
+		if len(buckets) == 0 {
+			return nil, fmt.Errorf("at least one bucket edge is required")
+		}
+
+		for _, b := range buckets {
+			edge, ok := numericValue(b)
+			if !ok {
+				return nil, fmt.Errorf("invalid bucket edge '%v'", b)
+			}
+
+			agg.edges = append(agg.edges, edge)
+		}
+
+		if !sort.Float64sAreSorted(agg.edges) {
+			return nil, fmt.Errorf("bucket edges should be in ascending order")
+		}
+
+	default:
+		count, ok := numericValue(buckets)
+		if !ok || count < 1 {
+			return nil, fmt.Errorf("buckets should be a list of edges or a positive number")
+		}
+
+		agg.count = int(count)
+	}
+
+	w, ok := opts["width"]
+	if ok {
+		width, ok := numericValue(w)
+		if !ok || width < 1 {
+			return nil, fmt.Errorf("width should be a positive number")
+		}
+
+		agg.width = int(width)
+	}
+
+	return agg, nil
+}
+
+// Type is the type of Aggregator
+func (h *HistogramAggregator) Type() string {
+	return "histogram"
+}
+
+// ProcessValue processes and tracks the specific value
+func (h *HistogramAggregator) ProcessValue(v interface{}) error {
+	h.Lock()
+	defer h.Unlock()
+
+	f, ok := numericValue(v)
+	if !ok {
+		return fmt.Errorf("unsupported data type for histogram aggregator")
+	}
+
+	h.items = append(h.items, f)
+
+	return nil
+}
+
+// ResultJSON return the buckets as a JSON document with the key "buckets", unbounded bucket edges are null
+func (h *HistogramAggregator) ResultJSON() ([]byte, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	return json.Marshal(map[string][]histogramBucket{
+		"buckets": h.buckets(),
+	})
+}
+
+// ResultStrings returns a map of bucket names and their counts
+func (h *HistogramAggregator) ResultStrings() (map[string]string, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	result := map[string]string{}
+	buckets := h.buckets()
+	labels := bucketLabels(buckets)
+
+	for i, b := range buckets {
+		result[labels[i]] = fmt.Sprintf("%d", b.Count)
+	}
+
+	return result, nil
+}
+
+// ResultFormattedStrings returns a horizontal bar chart of the buckets, when a format is
+// given it receives the bucket name and count and no bars are drawn
+func (h *HistogramAggregator) ResultFormattedStrings(format string) ([]string, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	output := []string{}
+	buckets := h.buckets()
+
+	if len(buckets) == 0 {
+		return output, nil
+	}
+
+	labels := bucketLabels(buckets)
+
+	if format == "" {
+		format = h.format
+	}
+
+	if format != "" {
+		for i, b := range buckets {
+			output = append(output, fmt.Sprintf(format, labels[i], b.Count))
+		}
+
+		return output, nil
+	}
+
+	max := 0
+	most := 0
+	for i, b := range buckets {
+		l := len(labels[i])
+		if l > max {
+			max = l
+		}
+
+		if b.Count > most {
+			most = b.Count
+		}
+	}
+
+	for i, b := range buckets {
+		bar := 0
+		if most > 0 {
+			bar = int(math.Round(float64(b.Count) / float64(most) * float64(h.width)))
+		}
+
+		output = append(output, fmt.Sprintf("%*s | %s %d", max, labels[i], strings.Repeat("█", bar), b.Count))
+	}
+
+	return output, nil
+}
+
+// buckets calculates the bucket counts, must be called with the lock held
+func (h *HistogramAggregator) buckets() []histogramBucket {
+	if len(h.items) == 0 {
+		return []histogramBucket{}
+	}
+
+	if len(h.edges) > 0 {
+		return h.explicitBuckets()
+	}
+
+	return h.automaticBuckets()
+}
+
+func (h *HistogramAggregator) explicitBuckets() []histogramBucket {
+	// one bucket below the first edge, one between each pair of edges and one above the last
+	buckets := make([]histogramBucket, len(h.edges)+1)
+
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].Low = &h.edges[i-1]
+		}
+
+		if i < len(h.edges) {
+			buckets[i].High = &h.edges[i]
+		}
+	}
+
+	for _, v := range h.items {
+		buckets[sort.Search(len(h.edges), func(i int) bool { return h.edges[i] > v })].Count++
+	}
+
+	// the unbounded buckets are only shown when they have values in them
+	result := []histogramBucket{}
+	for i, b := range buckets {
+		if (i == 0 || i == len(buckets)-1) && b.Count == 0 {
+			continue
+		}
+
+		result = append(result, b)
+	}
+
+	return result
+}
+
+func (h *HistogramAggregator) automaticBuckets() []histogramBucket {
+	min := h.items[0]
+	max := h.items[0]
+	for _, v := range h.items {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+
+	count := h.count
+	if min == max {
+		count = 1
+	}
+
+	step := (max - min) / float64(count)
+	buckets := make([]histogramBucket, count)
+
+	for i := range buckets {
+		low := min + step*float64(i)
+		high := min + step*float64(i+1)
+		if i == count-1 {
+			high = max
+		}
+
+		buckets[i].Low = &low
+		buckets[i].High = &high
+	}
+
+	for _, v := range h.items {
+		idx := count - 1
+		if step > 0 {
+			idx = int((v - min) / step)
+		}
+
+		// the largest value falls on the upper edge of the last bucket
+		if idx >= count {
+			idx = count - 1
+		}
+
+		buckets[idx].Count++
+	}
+
+	return buckets
+}
+
+// bucketLabels names the buckets, edges are rounded to 3 decimals unless that would give
+// adjacent buckets the same name in which case more precision is used
+func bucketLabels(buckets []histogramBucket) []string {
+	labels := make([]string, len(buckets))
+
+	for precision := 3; precision <= 17; precision++ {
+		seen := make(map[string]bool)
+		unique := true
+
+		for i, b := range buckets {
+			labels[i] = b.label(precision)
+			if seen[labels[i]] {
+				unique = false
+			}
+			seen[labels[i]] = true
+		}
+
+		if unique {
+			return labels
+		}
+	}
+
+	// buckets with identical edges are only possible when the range is too small to split
+	for i := range labels {
+		labels[i] = fmt.Sprintf("%s (%d)", labels[i], i+1)
+	}
+
+	return labels
+}
+
+func (b histogramBucket) label(precision int) string {
+	switch {
+	case b.Low == nil:
+		return fmt.Sprintf("< %s", formatEdge(*b.High, precision))
+	case b.High == nil:
+		return fmt.Sprintf(">= %s", formatEdge(*b.Low, precision))
+	default:
+		return fmt.Sprintf("%s - %s", formatEdge(*b.Low, precision), formatEdge(*b.High, precision))
+	}
+}
+
+func formatEdge(f float64, precision int) string {
+	if precision >= 17 {
+		return formatNumber(f)
+	}
+
+	scale := math.Pow(10, float64(precision))
+
+	return formatNumber(math.Round(f*scale) / scale)
+}
+
+// Merge merges the state of another HistogramAggregator into this one
+func (h *HistogramAggregator) Merge(other Aggregator) error {
+	return mergeAggregator(h, other)
+}
+
+type histogramState struct {
+	Items []float64 `json:"items"`
+}
+
+// State returns the internal state of the aggregator in JSON format
+func (h *HistogramAggregator) State() ([]byte, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	return json.Marshal(histogramState{Items: h.items})
+}
+
+// MergeState merges a state previously produced by State() into this aggregator
+func (h *HistogramAggregator) MergeState(state []byte) error {
+	hs := histogramState{}
+	err := json.Unmarshal(state, &hs)
+	if err != nil {
+		return fmt.Errorf("invalid histogram aggregator state: %s", err)
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	h.items = append(h.items, hs.Items...)
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package aggregate
+
+import (
+	"math"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HistogramAggregator", func() {
+	var (
+		err error
+		agg *HistogramAggregator
+	)
+
+	BeforeEach(func() {
+		agg, err = NewHistogramAggregator([]interface{}{"x", map[string]interface{}{"buckets": 4.0, "width": 10.0}})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("NewHistogramAggregator", func() {
+		It("Should default to 10 buckets", func() {
+			agg, err = NewHistogramAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(agg.count).To(Equal(10))
+			Expect(agg.edges).To(BeEmpty())
+		})
+
+		It("Should support explicit edges", func() {
+			agg, err = NewHistogramAggregator([]interface{}{"x", map[string]interface{}{"buckets": []interface{}{10.0, 100.0}}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(agg.edges).To(Equal([]float64{10, 100}))
+		})
+
+		It("Should detect invalid buckets", func() {
+			_, err = NewHistogramAggregator([]interface{}{"x", map[string]interface{}{"buckets": []interface{}{100.0, 10.0}}})
+			Expect(err).To(MatchError("bucket edges should be in ascending order"))
+
+			_, err = NewHistogramAggregator([]interface{}{"x", map[string]interface{}{"buckets": 0.0}})
+			Expect(err).To(MatchError("buckets should be a list of edges or a positive number"))
+		})
+	})
+
+	Describe("ProcessValue", func() {
+		It("Should process various values", func() {
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(1.5)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(int64(2))).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("3")).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("a")).To(HaveOccurred())
+			Expect(agg.ProcessValue("Inf")).To(HaveOccurred())
+			Expect(agg.ProcessValue("-Inf")).To(HaveOccurred())
+			Expect(agg.ProcessValue("NaN")).To(HaveOccurred())
+			Expect(agg.ProcessValue(math.Inf(1))).To(HaveOccurred())
+
+			Expect(agg.items).To(Equal([]float64{1, 1.5, 2, 3}))
+
+			_, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("Automatic buckets", func() {
+		BeforeEach(func() {
+			for _, v := range []int{0, 1, 1, 3, 4, 5, 8} {
+				Expect(agg.ProcessValue(v)).ToNot(HaveOccurred())
+			}
+		})
+
+		It("Should produce correct strings", func() {
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{
+				"0 - 2": "3",
+				"2 - 4": "1",
+				"4 - 6": "2",
+				"6 - 8": "1",
+			}))
+		})
+
+		It("Should produce a bar chart", func() {
+			results, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal([]string{
+				"0 - 2 | ██████████ 3",
+				"2 - 4 | ███ 1",
+				"4 - 6 | ███████ 2",
+				"6 - 8 | ███ 1",
+			}))
+
+			results, err = agg.ResultFormattedStrings("%s: %d")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results[0]).To(Equal("0 - 2: 3"))
+		})
+
+		It("Should produce correct JSON", func() {
+			results, err := agg.ResultJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(MatchJSON(`{"buckets":[{"low":0,"high":2,"count":3},{"low":2,"high":4,"count":1},{"low":4,"high":6,"count":2},{"low":6,"high":8,"count":1}]}`))
+		})
+
+		It("Should keep labels unique for small ranges", func() {
+			agg, err = NewHistogramAggregator([]interface{}{"x", map[string]interface{}{"buckets": 4}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(1.0001)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(1.0004)).ToNot(HaveOccurred())
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{
+				"1 - 1.0001":      "1",
+				"1.0001 - 1.0002": "1",
+				"1.0002 - 1.0003": "0",
+				"1.0003 - 1.0004": "1",
+			}))
+		})
+
+		It("Should handle identical values", func() {
+			agg, err = NewHistogramAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(5)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(5)).ToNot(HaveOccurred())
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{"5 - 5": "2"}))
+		})
+	})
+
+	Describe("Explicit buckets", func() {
+		BeforeEach(func() {
+			agg, err = NewHistogramAggregator([]interface{}{"x", map[string]interface{}{"buckets": []interface{}{10.0, 100.0, 1000.0}}})
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, v := range []int{10, 50, 99, 100, 5000} {
+				Expect(agg.ProcessValue(v)).ToNot(HaveOccurred())
+			}
+		})
+
+		It("Should only show unbounded buckets with values", func() {
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{
+				"10 - 100":   "3",
+				"100 - 1000": "1",
+				">= 1000":    "1",
+			}))
+		})
+
+		It("Should produce correct JSON", func() {
+			results, err := agg.ResultJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(MatchJSON(`{"buckets":[{"low":10,"high":100,"count":3},{"low":100,"high":1000,"count":1},{"low":1000,"high":null,"count":1}]}`))
+		})
+	})
+
+	Describe("Merge", func() {
+		It("Should merge the other values", func() {
+			other, err := NewHistogramAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(other.ProcessValue(2)).ToNot(HaveOccurred())
+
+			Expect(agg.Merge(other)).ToNot(HaveOccurred())
+			Expect(agg.items).To(Equal([]float64{1, 2}))
+		})
+	})
+})
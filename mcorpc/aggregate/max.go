@@ -0,0 +1,18 @@
+package aggregate
+
+// MaxAggregator tracks the largest seen value
+type MaxAggregator struct {
+	extremeAggregator
+}
+
+// NewMaxAggregator creates a new MaxAggregator with the specific options supplied
+func NewMaxAggregator(args []interface{}) (*MaxAggregator, error) {
+	better := func(f float64, current float64) bool { return f > current }
+
+	return &MaxAggregator{newExtremeAggregator("max", "Maximum", better, args)}, nil
+}
+
+// Merge merges the state of another MaxAggregator into this one
+func (a *MaxAggregator) Merge(other Aggregator) error {
+	return mergeAggregator(a, other)
+}
@@ -0,0 +1,18 @@
+package aggregate
+
+// MinAggregator tracks the smallest seen value
+type MinAggregator struct {
+	extremeAggregator
+}
+
+// NewMinAggregator creates a new MinAggregator with the specific options supplied
+func NewMinAggregator(args []interface{}) (*MinAggregator, error) {
+	better := func(f float64, current float64) bool { return f < current }
+
+	return &MinAggregator{newExtremeAggregator("min", "Minimum", better, args)}, nil
+}
+
+// Merge merges the state of another MinAggregator into this one
+func (a *MinAggregator) Merge(other Aggregator) error {
+	return mergeAggregator(a, other)
+}
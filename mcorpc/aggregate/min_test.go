@@ -0,0 +1,84 @@
+package aggregate
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MinAggregator", func() {
+	var (
+		err error
+		agg *MinAggregator
+	)
+
+	BeforeEach(func() {
+		agg, err = NewMinAggregator([]interface{}{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("ProcessValue", func() {
+		It("Should process various values", func() {
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(-1.5)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(int64(100))).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("10")).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("a")).To(HaveOccurred())
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{
+				"Minimum": "-1.5",
+			}))
+
+			fresults, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fresults).To(Equal([]string{
+				"Minimum: -1.5",
+			}))
+
+			jresults, err := agg.ResultJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jresults).To(MatchJSON(`{"min":-1.5}`))
+		})
+		It("Should support integer formats", func() {
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(int64(100))).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("10")).ToNot(HaveOccurred())
+			fresults, err := agg.ResultFormattedStrings("Minimum: %d")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fresults).To(Equal([]string{"Minimum: 1"}))
+		})
+	})
+
+	Describe("Empty results", func() {
+		It("Should handle no values", func() {
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(BeEmpty())
+
+			fresults, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fresults).To(BeEmpty())
+
+			jresults, err := agg.ResultJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jresults).To(MatchJSON(`{"min":null}`))
+		})
+	})
+
+	Describe("Merge", func() {
+		It("Should merge the other value", func() {
+			other, err := NewMinAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(agg.Merge(other)).ToNot(HaveOccurred())
+			Expect(agg.seen).To(BeFalse())
+
+			Expect(agg.ProcessValue(5)).ToNot(HaveOccurred())
+			Expect(other.ProcessValue(2)).ToNot(HaveOccurred())
+
+			Expect(agg.Merge(other)).ToNot(HaveOccurred())
+			Expect(agg.value).To(Equal(float64(2)))
+		})
+	})
+})
@@ -0,0 +1,264 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// StatsAggregator tracks seen numeric values and calculates descriptive statistics and percentiles
+type StatsAggregator struct {
+	items       []float64
+	percentiles []float64
+	format      string
+
+	sync.Mutex
+}
+
+type statsItem struct {
+	key   string
+	value float64
+}
+
+// NewStatsAggregator creates a new StatsAggregator with the specific options supplied
+//
+// Supported options are "percentiles", a list of percentiles to calculate that defaults
+// to 90, 95 and 99, and "format" which is used for formatted results
+func NewStatsAggregator(args []interface{}) (*StatsAggregator, error) {
+	agg := &StatsAggregator{
+		items:       []float64{},
+		percentiles: []float64{90, 95, 99},
+		format:      aggregatorFormat(args),
+	}
+
+	opts := aggregatorOptions(args)
+
+	p, ok := opts["percentiles"]
+	if ok {
+		list, ok := p.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("percentiles should be a list of numbers")
+		}
+
+		agg.percentiles = []float64{}
+
+		for _, i := range list {
+			pct, ok := numericValue(i)
+			if !ok {
+				return nil, fmt.Errorf("invalid percentile '%v'", i)
+			}
+
+			if pct < 0 || pct > 100 {
+				return nil, fmt.Errorf("percentile %v is not between 0 and 100", i)
+			}
+
+			agg.percentiles = append(agg.percentiles, pct)
+		}
+	}
+
+	return agg, nil
+}
+
+// Type is the type of Aggregator
+func (s *StatsAggregator) Type() string {
+	return "stats"
+}
+
+// ProcessValue processes and tracks the specific value
+func (s *StatsAggregator) ProcessValue(v interface{}) error {
+	s.Lock()
+	defer s.Unlock()
+
+	f, ok := numericValue(v)
+	if !ok {
+		return fmt.Errorf("unsupported data type for stats aggregator")
+	}
+
+	s.items = append(s.items, f)
+
+	return nil
+}
+
+// ResultJSON return the results in JSON format preserving types
+func (s *StatsAggregator) ResultJSON() ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	result := map[string]interface{}{
+		"count": len(s.items),
+	}
+
+	for _, stat := range s.statistics() {
+		result[stat.key] = stat.value
+	}
+
+	return json.Marshal(result)
+}
+
+// ResultStrings returns a map of results in string format
+func (s *StatsAggregator) ResultStrings() (map[string]string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	result := map[string]string{}
+
+	if len(s.items) == 0 {
+		return result, nil
+	}
+
+	result["Count"] = strconv.Itoa(len(s.items))
+
+	for _, stat := range s.statistics() {
+		result[statsTitle(stat.key)] = fmt.Sprintf("%f", stat.value)
+	}
+
+	return result, nil
+}
+
+// ResultFormattedStrings return the results in a formatted way, if no format is given a calculated value is used
+//
+// The format receives the name and value of each statistic, the count is always shown first as an integer
+func (s *StatsAggregator) ResultFormattedStrings(format string) ([]string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	output := []string{}
+
+	if len(s.items) == 0 {
+		return output, nil
+	}
+
+	stats := s.statistics()
+
+	max := len("Count")
+	for _, stat := range stats {
+		l := len(statsTitle(stat.key))
+		if l > max {
+			max = l
+		}
+	}
+
+	if format == "" {
+		format = s.format
+	}
+
+	if format == "" {
+		format = fmt.Sprintf("%%%ds: %%.3f", max)
+	}
+
+	output = append(output, fmt.Sprintf(fmt.Sprintf("%%%ds: %%d", max), "Count", len(s.items)))
+
+	for _, stat := range stats {
+		output = append(output, fmt.Sprintf(format, statsTitle(stat.key), stat.value))
+	}
+
+	return output, nil
+}
+
+// statistics calculates all the statistics in display order, must be called with the lock held
+func (s *StatsAggregator) statistics() []statsItem {
+	if len(s.items) == 0 {
+		return []statsItem{}
+	}
+
+	sorted := make([]float64, len(s.items))
+	copy(sorted, s.items)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+
+	mean := sum / float64(len(sorted))
+
+	variance := 0.0
+	for _, v := range sorted {
+		variance += math.Pow(v-mean, 2)
+	}
+	variance = variance / float64(len(sorted))
+
+	result := []statsItem{
+		{"min", sorted[0]},
+		{"max", sorted[len(sorted)-1]},
+		{"mean", mean},
+		{"median", percentile(sorted, 50)},
+		{"stddev", math.Sqrt(variance)},
+	}
+
+	for _, p := range s.percentiles {
+		result = append(result, statsItem{"p" + strconv.FormatFloat(p, 'f', -1, 64), percentile(sorted, p)})
+	}
+
+	return result
+}
+
+// percentile calculates the p'th percentile of sorted by interpolating between the closest ranks
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+
+	rank := (p / 100) * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	if lower == upper {
+		return sorted[lower]
+	}
+
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func statsTitle(key string) string {
+	switch key {
+	case "min":
+		return "Minimum"
+	case "max":
+		return "Maximum"
+	case "mean":
+		return "Mean"
+	case "median":
+		return "Median"
+	case "stddev":
+		return "Std Deviation"
+	default:
+		return key
+	}
+}
+
+// Merge merges the state of another StatsAggregator into this one
+func (s *StatsAggregator) Merge(other Aggregator) error {
+	return mergeAggregator(s, other)
+}
+
+type statsState struct {
+	Items []float64 `json:"items"`
+}
+
+// State returns the internal state of the aggregator in JSON format
+func (s *StatsAggregator) State() ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return json.Marshal(statsState{Items: s.items})
+}
+
+// MergeState merges a state previously produced by State() into this aggregator
+func (s *StatsAggregator) MergeState(state []byte) error {
+	ss := statsState{}
+	err := json.Unmarshal(state, &ss)
+	if err != nil {
+		return fmt.Errorf("invalid stats aggregator state: %s", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.items = append(s.items, ss.Items...)
+
+	return nil
+}
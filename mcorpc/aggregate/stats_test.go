@@ -0,0 +1,143 @@
+package aggregate
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StatsAggregator", func() {
+	var (
+		err error
+		agg *StatsAggregator
+	)
+
+	BeforeEach(func() {
+		agg, err = NewStatsAggregator([]interface{}{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("NewStatsAggregator", func() {
+		It("Should support custom percentiles", func() {
+			agg, err = NewStatsAggregator([]interface{}{"x", map[string]interface{}{"percentiles": []interface{}{50.0, 99.9}}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(agg.percentiles).To(Equal([]float64{50, 99.9}))
+		})
+
+		It("Should detect invalid percentiles", func() {
+			_, err = NewStatsAggregator([]interface{}{"x", map[string]interface{}{"percentiles": []interface{}{101.0}}})
+			Expect(err).To(MatchError("percentile 101 is not between 0 and 100"))
+
+			_, err = NewStatsAggregator([]interface{}{"x", map[string]interface{}{"percentiles": "90"}})
+			Expect(err).To(MatchError("percentiles should be a list of numbers"))
+		})
+	})
+
+	Describe("ProcessValue", func() {
+		It("Should process various values", func() {
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(1.1)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(int64(100))).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("1")).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("a")).To(HaveOccurred())
+			Expect(agg.ProcessValue("NaN")).To(HaveOccurred())
+			Expect(agg.ProcessValue("-Inf")).To(HaveOccurred())
+
+			Expect(agg.items).To(Equal([]float64{1, 1.1, 100, 1}))
+
+			_, err := agg.ResultJSON()
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("Results", func() {
+		BeforeEach(func() {
+			for i := 10; i > 0; i-- {
+				Expect(agg.ProcessValue(i)).ToNot(HaveOccurred())
+			}
+		})
+
+		It("Should produce correct strings", func() {
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{
+				"Count":         "10",
+				"Minimum":       "1.000000",
+				"Maximum":       "10.000000",
+				"Mean":          "5.500000",
+				"Median":        "5.500000",
+				"Std Deviation": "2.872281",
+				"p90":           "9.100000",
+				"p95":           "9.550000",
+				"p99":           "9.910000",
+			}))
+		})
+
+		It("Should produce correct formatted strings", func() {
+			results, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal([]string{
+				"        Count: 10",
+				"      Minimum: 1.000",
+				"      Maximum: 10.000",
+				"         Mean: 5.500",
+				"       Median: 5.500",
+				"Std Deviation: 2.872",
+				"          p90: 9.100",
+				"          p95: 9.550",
+				"          p99: 9.910",
+			}))
+
+			results, err = agg.ResultFormattedStrings("%s=%.1f")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results[1]).To(Equal("Minimum=1.0"))
+		})
+
+		It("Should produce correct JSON", func() {
+			results, err := agg.ResultJSON()
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed := map[string]float64{}
+			Expect(json.Unmarshal(results, &parsed)).ToNot(HaveOccurred())
+
+			expected := map[string]float64{"count": 10, "min": 1, "max": 10, "mean": 5.5, "median": 5.5, "stddev": 2.872281, "p90": 9.1, "p95": 9.55, "p99": 9.91}
+			Expect(parsed).To(HaveLen(len(expected)))
+			for k, v := range expected {
+				Expect(parsed[k]).To(BeNumerically("~", v, 0.000001), k)
+			}
+		})
+	})
+
+	Describe("Empty results", func() {
+		It("Should handle no values", func() {
+			results, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(BeEmpty())
+
+			jresults, err := agg.ResultJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jresults).To(MatchJSON(`{"count":0}`))
+		})
+	})
+
+	Describe("Merge", func() {
+		It("Should merge the other values exactly", func() {
+			other, err := NewStatsAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 1; i <= 5; i++ {
+				Expect(agg.ProcessValue(i)).ToNot(HaveOccurred())
+				Expect(other.ProcessValue(i + 5)).ToNot(HaveOccurred())
+			}
+
+			Expect(agg.Merge(other)).ToNot(HaveOccurred())
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results["Count"]).To(Equal("10"))
+			Expect(results["Median"]).To(Equal("5.500000"))
+			Expect(results["p90"]).To(Equal("9.100000"))
+		})
+	})
+})
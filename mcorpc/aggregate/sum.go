@@ -0,0 +1,111 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// SumAggregator sums seen values
+type SumAggregator struct {
+	sum    float64
+	format string
+
+	sync.Mutex
+}
+
+// NewSumAggregator creates a new SumAggregator with the specific options supplied
+func NewSumAggregator(args []interface{}) (*SumAggregator, error) {
+	agg := &SumAggregator{
+		format: aggregatorFormat(args),
+	}
+
+	return agg, nil
+}
+
+// Type is the type of Aggregator
+func (a *SumAggregator) Type() string {
+	return "sum"
+}
+
+// ProcessValue processes and tracks the specific value
+func (a *SumAggregator) ProcessValue(v interface{}) error {
+	a.Lock()
+	defer a.Unlock()
+
+	f, ok := numericValue(v)
+	if !ok {
+		return fmt.Errorf("unsupported data type for sum aggregator")
+	}
+
+	a.sum = a.sum + f
+
+	return nil
+}
+
+// ResultJSON return the results in JSON format preserving types
+func (a *SumAggregator) ResultJSON() ([]byte, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	return json.Marshal(map[string]float64{
+		"sum": a.sum,
+	})
+}
+
+// ResultStrings returns a map of results in string format
+func (a *SumAggregator) ResultStrings() (map[string]string, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	return map[string]string{"Sum": formatNumber(a.sum)}, nil
+}
+
+// ResultFormattedStrings return the results in a formatted way, if no format is given a calculated value is used
+func (a *SumAggregator) ResultFormattedStrings(format string) ([]string, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if format == "" {
+		format = a.format
+	}
+
+	if format == "" {
+		return []string{fmt.Sprintf("Sum: %s", formatNumber(a.sum))}, nil
+	}
+
+	return []string{sprintfNumber(format, a.sum)}, nil
+}
+
+// Merge merges the state of another SumAggregator into this one
+func (a *SumAggregator) Merge(other Aggregator) error {
+	return mergeAggregator(a, other)
+}
+
+type sumState struct {
+	Sum float64 `json:"sum"`
+}
+
+// State returns the internal state of the aggregator in JSON format
+func (a *SumAggregator) State() ([]byte, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	return json.Marshal(sumState{Sum: a.sum})
+}
+
+// MergeState merges a state previously produced by State() into this aggregator
+func (a *SumAggregator) MergeState(state []byte) error {
+	s := sumState{}
+	err := json.Unmarshal(state, &s)
+	if err != nil {
+		return fmt.Errorf("invalid sum aggregator state: %s", err)
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	a.sum = a.sum + s.Sum
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package aggregate
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SumAggregator", func() {
+	var (
+		err error
+		agg *SumAggregator
+	)
+
+	BeforeEach(func() {
+		agg, err = NewSumAggregator([]interface{}{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("ProcessValue", func() {
+		It("Should process various values", func() {
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(1.5)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(int64(100))).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("1")).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("a")).To(HaveOccurred())
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{
+				"Sum": "103.5",
+			}))
+
+			fresults, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fresults).To(Equal([]string{
+				"Sum: 103.5",
+			}))
+
+			fresults, err = agg.ResultFormattedStrings("Total: %.2f")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fresults).To(Equal([]string{
+				"Total: 103.50",
+			}))
+
+			jresults, err := agg.ResultJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jresults).To(MatchJSON(`{"sum":103.5}`))
+		})
+
+		It("Should use the format from the arguments", func() {
+			agg, err = NewSumAggregator([]interface{}{"x", map[string]interface{}{"format": "Updated: %.0f"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(10)).ToNot(HaveOccurred())
+
+			fresults, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fresults).To(Equal([]string{"Updated: 10"}))
+		})
+
+		It("Should support integer formats", func() {
+			agg, err = NewSumAggregator([]interface{}{"x", map[string]interface{}{"format": "Updated: %d"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(10)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("2")).ToNot(HaveOccurred())
+
+			fresults, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fresults).To(Equal([]string{"Updated: 12"}))
+
+			fresults, err = agg.ResultFormattedStrings("%.1f%% total")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fresults).To(Equal([]string{"12.0% total"}))
+		})
+	})
+
+	Describe("Merge", func() {
+		It("Should add the other sums", func() {
+			other, err := NewSumAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(other.ProcessValue(2.5)).ToNot(HaveOccurred())
+
+			Expect(agg.Merge(other)).ToNot(HaveOccurred())
+			Expect(agg.sum).To(Equal(3.5))
+		})
+	})
+})
@@ -3,6 +3,7 @@ package aggregate
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"sort"
 	"sync"
 )
@@ -28,11 +29,23 @@ func (s *SummaryAggregator) Type() string {
 	return "summary"
 }
 
-// ProcessValue processes and tracks a specified value
+// ProcessValue processes and tracks a specified value, hashes and arrays are tracked
+// using their JSON representation
 func (s *SummaryAggregator) ProcessValue(v interface{}) error {
 	s.Lock()
 	defer s.Unlock()
 
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		// json sorts map keys so this is a canonical form
+		j, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("could not serialize value for summary aggregator: %s", err)
+		}
+
+		v = string(j)
+	}
+
 	_, ok := s.items[v]
 	if !ok {
 		s.items[v] = 0
@@ -117,3 +130,55 @@ func (s *SummaryAggregator) ResultFormattedStrings(format string) ([]string, err
 
 	return output, nil
 }
+
+// Merge merges the state of another SummaryAggregator into this one
+func (s *SummaryAggregator) Merge(other Aggregator) error {
+	return mergeAggregator(s, other)
+}
+
+type summaryState struct {
+	Items map[string]int `json:"items"`
+}
+
+// State returns the internal state of the aggregator in JSON format, values are
+// stored in the string form used when producing results
+func (s *SummaryAggregator) State() ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	state := summaryState{Items: make(map[string]int)}
+	for k, v := range s.items {
+		state.Items[fmt.Sprintf("%v", k)] += v
+	}
+
+	return json.Marshal(state)
+}
+
+// MergeState merges a state previously produced by State() into this aggregator
+func (s *SummaryAggregator) MergeState(state []byte) error {
+	ss := summaryState{}
+	err := json.Unmarshal(state, &ss)
+	if err != nil {
+		return fmt.Errorf("invalid summary aggregator state: %s", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	// values seen locally might not be strings, match them up with the merged ones using their string form
+	known := make(map[string]interface{})
+	for k := range s.items {
+		known[fmt.Sprintf("%v", k)] = k
+	}
+
+	for k, v := range ss.Items {
+		key, ok := known[k]
+		if !ok {
+			key = k
+		}
+
+		s.items[key] += v
+	}
+
+	return nil
+}
@@ -30,6 +30,21 @@ var _ = Describe("SummaryAggregator", func() {
 		})
 	})
 
+	Describe("Unhashable values", func() {
+		It("Should summarize hashes and arrays by their JSON representation", func() {
+			Expect(agg.ProcessValue(map[string]interface{}{"b": 1, "a": 2})).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue(map[string]interface{}{"a": 2, "b": 1})).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue([]interface{}{"x", "y"})).ToNot(HaveOccurred())
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{
+				`{"a":2,"b":1}`: "2",
+				`["x","y"]`:     "1",
+			}))
+		})
+	})
+
 	Describe("FormattedStrings", func() {
 		It("Should calculate a correct width format", func() {
 			Expect(agg.ProcessValue("med")).ToNot(HaveOccurred())
@@ -71,4 +86,23 @@ var _ = Describe("SummaryAggregator", func() {
 			Expect(jresults).To(MatchJSON("{\"1\":2, \"looooong\":3, \"med\":1}"))
 		})
 	})
+
+	Describe("Merge", func() {
+		It("Should merge other summaries", func() {
+			other, err := NewSummaryAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(agg.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(agg.ProcessValue("a")).ToNot(HaveOccurred())
+			Expect(other.ProcessValue(1)).ToNot(HaveOccurred())
+			Expect(other.ProcessValue("b")).ToNot(HaveOccurred())
+
+			Expect(agg.Merge(other)).ToNot(HaveOccurred())
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{"1": "2", "a": "1", "b": "1"}))
+			Expect(agg.items).To(HaveLen(3))
+		})
+	})
 })
@@ -0,0 +1,195 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// TopNAggregator keeps track of seen values and reports the N most common ones, all
+// other values are summarized in a single other bucket
+type TopNAggregator struct {
+	items  map[string]int
+	n      int
+	format string
+
+	sync.Mutex
+}
+
+type topNItem struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// NewTopNAggregator creates a new TopNAggregator with the specific options supplied
+//
+// Supported options are "count", the number of values to report that defaults to 10 and
+// "format" which is used for formatted results and receives the value and count
+func NewTopNAggregator(args []interface{}) (*TopNAggregator, error) {
+	agg := &TopNAggregator{
+		items:  make(map[string]int),
+		n:      10,
+		format: aggregatorFormat(args),
+	}
+
+	c, ok := aggregatorOptions(args)["count"]
+	if ok {
+		count, ok := numericValue(c)
+		if !ok || count < 1 {
+			return nil, fmt.Errorf("count should be a positive number")
+		}
+
+		agg.n = int(count)
+	}
+
+	return agg, nil
+}
+
+// Type is the type of Aggregator
+func (t *TopNAggregator) Type() string {
+	return "topn"
+}
+
+// ProcessValue processes and tracks a specified value
+func (t *TopNAggregator) ProcessValue(v interface{}) error {
+	t.Lock()
+	defer t.Unlock()
+
+	t.items[fmt.Sprintf("%v", v)]++
+
+	return nil
+}
+
+// ResultStrings returns a map of results in string format, the other bucket is in the key "Other"
+func (t *TopNAggregator) ResultStrings() (map[string]string, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	result := map[string]string{}
+
+	top, other := t.top()
+	for _, i := range top {
+		result[i.Value] = fmt.Sprintf("%d", i.Count)
+	}
+
+	if other > 0 {
+		result["Other"] = fmt.Sprintf("%d", other)
+	}
+
+	return result, nil
+}
+
+// ResultJSON return the results in JSON format with the most common values in "top" and the rest counted in "other"
+func (t *TopNAggregator) ResultJSON() ([]byte, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	top, other := t.top()
+
+	return json.Marshal(map[string]interface{}{
+		"top":   top,
+		"other": other,
+	})
+}
+
+// ResultFormattedStrings return the results in a formatted way, if no format is given a calculated value is used
+func (t *TopNAggregator) ResultFormattedStrings(format string) ([]string, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	output := []string{}
+
+	top, other := t.top()
+	if len(top) == 0 {
+		return output, nil
+	}
+
+	if other > 0 {
+		top = append(top, topNItem{"Other", other})
+	}
+
+	max := 0
+	for _, i := range top {
+		l := len(i.Value)
+		if l > max {
+			max = l
+		}
+	}
+
+	if format == "" {
+		format = t.format
+	}
+
+	if format == "" {
+		format = fmt.Sprintf("%%%ds: %%d", max)
+	}
+
+	for _, i := range top {
+		output = append(output, fmt.Sprintf(format, i.Value, i.Count))
+	}
+
+	return output, nil
+}
+
+// top calculates the most common values and the count of all other values, must be called with the lock held
+func (t *TopNAggregator) top() (top []topNItem, other int) {
+	top = []topNItem{}
+
+	for k, v := range t.items {
+		top = append(top, topNItem{k, v})
+	}
+
+	sort.Slice(top, func(i int, j int) bool {
+		if top[i].Count == top[j].Count {
+			return top[i].Value < top[j].Value
+		}
+
+		return top[i].Count > top[j].Count
+	})
+
+	if len(top) <= t.n {
+		return top, 0
+	}
+
+	for _, i := range top[t.n:] {
+		other += i.Count
+	}
+
+	return top[:t.n], other
+}
+
+// Merge merges the state of another TopNAggregator into this one
+func (t *TopNAggregator) Merge(other Aggregator) error {
+	return mergeAggregator(t, other)
+}
+
+type topNState struct {
+	Items map[string]int `json:"items"`
+}
+
+// State returns the internal state of the aggregator in JSON format
+func (t *TopNAggregator) State() ([]byte, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	return json.Marshal(topNState{Items: t.items})
+}
+
+// MergeState merges a state previously produced by State() into this aggregator
+func (t *TopNAggregator) MergeState(state []byte) error {
+	s := topNState{}
+	err := json.Unmarshal(state, &s)
+	if err != nil {
+		return fmt.Errorf("invalid topn aggregator state: %s", err)
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	for k, v := range s.Items {
+		t.items[k] += v
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package aggregate
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TopNAggregator", func() {
+	var (
+		err error
+		agg *TopNAggregator
+	)
+
+	BeforeEach(func() {
+		agg, err = NewTopNAggregator([]interface{}{"x", map[string]interface{}{"count": 2.0}})
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, v := range []interface{}{"3.10", "3.10", "3.10", "4.18", "4.18", "5.4", 1} {
+			Expect(agg.ProcessValue(v)).ToNot(HaveOccurred())
+		}
+	})
+
+	Describe("NewTopNAggregator", func() {
+		It("Should default to 10 items", func() {
+			agg, err = NewTopNAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(agg.n).To(Equal(10))
+		})
+
+		It("Should detect invalid counts", func() {
+			_, err = NewTopNAggregator([]interface{}{"x", map[string]interface{}{"count": 0.0}})
+			Expect(err).To(MatchError("count should be a positive number"))
+		})
+	})
+
+	Describe("ResultStrings", func() {
+		It("Should produce the top values and other", func() {
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{
+				"3.10":  "3",
+				"4.18":  "2",
+				"Other": "2",
+			}))
+		})
+	})
+
+	Describe("ResultFormattedStrings", func() {
+		It("Should calculate a correct width format", func() {
+			results, err := agg.ResultFormattedStrings("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal([]string{
+				" 3.10: 3",
+				" 4.18: 2",
+				"Other: 2",
+			}))
+		})
+
+		It("Should not show other when all values fit", func() {
+			agg.n = 5
+
+			results, err := agg.ResultFormattedStrings("%s=%d")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal([]string{"3.10=3", "4.18=2", "1=1", "5.4=1"}))
+		})
+	})
+
+	Describe("ResultJSON", func() {
+		It("Should produce correct JSON", func() {
+			results, err := agg.ResultJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(MatchJSON(`{"top":[{"value":"3.10","count":3},{"value":"4.18","count":2}],"other":2}`))
+		})
+	})
+
+	Describe("Merge", func() {
+		It("Should merge other counts", func() {
+			other, err := NewTopNAggregator([]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(other.ProcessValue("5.4")).ToNot(HaveOccurred())
+			Expect(other.ProcessValue("5.4")).ToNot(HaveOccurred())
+			Expect(other.ProcessValue("5.4")).ToNot(HaveOccurred())
+
+			Expect(agg.Merge(other)).ToNot(HaveOccurred())
+
+			results, err := agg.ResultStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal(map[string]string{
+				"5.4":   "4",
+				"3.10":  "3",
+				"Other": "3",
+			}))
+		})
+	})
+})
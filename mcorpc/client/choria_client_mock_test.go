@@ -46,6 +46,18 @@ func (mr *MockRequestResultMockRecorder) Stats() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockRequestResult)(nil).Stats))
 }
 
+// BatchStats mocks base method
+func (m *MockRequestResult) BatchStats() []*Stats {
+	ret := m.ctrl.Call(m, "BatchStats")
+	ret0, _ := ret[0].([]*Stats)
+	return ret0
+}
+
+// BatchStats indicates an expected call of BatchStats
+func (mr *MockRequestResultMockRecorder) BatchStats() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchStats", reflect.TypeOf((*MockRequestResult)(nil).BatchStats))
+}
+
 // MockChoriaClient is a mock of ChoriaClient interface
 type MockChoriaClient struct {
 	ctrl     *gomock.Controller
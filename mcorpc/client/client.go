@@ -3,10 +3,10 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 
-	"github.com/choria-io/go-client/discovery/broadcast"
 	config "github.com/choria-io/go-config"
 
 	"github.com/choria-io/go-choria/choria"
@@ -65,8 +65,23 @@ type RPCReply struct {
 // RequestResult is the result of a request
 type RequestResult interface {
 	Stats() *Stats
+	BatchStats() []*Stats
 }
 
+var (
+	// ErrRequestCancelled is returned by Do when the context was cancelled before all batches completed
+	ErrRequestCancelled = errors.New("request cancelled")
+
+	// ErrDiscoveryFailed is returned by Do when nodes could not be discovered
+	ErrDiscoveryFailed = errors.New("discovery failed")
+
+	// ErrPublishFailed is returned by Do when the request could not be sent to a batch of nodes
+	ErrPublishFailed = errors.New("publish failed")
+
+	// ErrInvalidRequest is returned by Do when the request could not be encoded or its options are invalid
+	ErrInvalidRequest = errors.New("invalid request")
+)
+
 // Handler is a function that should handle each reply synchronously
 type Handler func(protocol.Reply, *RPCReply)
 
@@ -109,7 +124,7 @@ func New(fw ChoriaFramework, agent string, opts ...Option) (rpc *RPC, err error)
 	}
 
 	if rpc.ddl == nil {
-		rpc.ddl, err = addl.Find(agent, rpc.cfg.LibDir)
+		rpc.ddl, err = addl.SharedRegistry(rpc.cfg.LibDir).Find(agent)
 		if err != nil {
 			return nil, fmt.Errorf("could not load %s DDL: %s", agent, err)
 		}
@@ -138,7 +153,12 @@ func (r *RPC) setOptions(opts ...RequestOption) (err error) {
 // Do performs a RPC request and optionally processes replies
 //
 // If a filter is supplied using the Filter() option and Targets() are not then discovery will be done for you
-// using the broadcast method, should no nodes be discovered an error will be returned
+// using the method set with DiscoveryMethod(), broadcast by default, should no nodes be discovered an error
+// will be returned
+//
+// A RequestResult is returned even when an error is returned, it holds the stats for the batches that were
+// completed and the nodes that were not contacted. Errors wrap ErrRequestCancelled, ErrDiscoveryFailed,
+// ErrPublishFailed or ErrInvalidRequest where appropriate, use errors.Is() to check for these
 func (r *RPC) Do(ctx context.Context, action string, payload interface{}, opts ...RequestOption) (RequestResult, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -146,29 +166,46 @@ func (r *RPC) Do(ctx context.Context, action string, payload interface{}, opts .
 	// we want to force the passing of options on every request
 	err := r.setOptions(opts...)
 	if err != nil {
-		return nil, err
+		return &RequestOptions{totalStats: NewStats()}, err
 	}
 
+	r.opts.action = action
+
 	dctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	if len(r.opts.Targets) == 0 {
 		err := r.discover(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("discovery failed: %s", err)
+			if ctx.Err() != nil {
+				return r.result(), fmt.Errorf("%w: %s", ErrRequestCancelled, err)
+			}
+
+			return r.result(), fmt.Errorf("%w: %s", ErrDiscoveryFailed, err)
 		}
 	}
 
 	discoveredCnt := len(r.opts.Targets)
 	msg, cl, err := r.setupMessage(dctx, action, payload, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("could not configure message: %s", err)
+		if ctx.Err() != nil {
+			return r.result(), fmt.Errorf("%w: %s", ErrRequestCancelled, err)
+		}
+
+		return r.result(), fmt.Errorf("could not configure message: %w", err)
 	}
 
 	if r.opts.DiscoveryEndCB != nil {
 		err = r.opts.DiscoveryEndCB(discoveredCnt, len(r.opts.Targets))
 		if err != nil {
-			return nil, err
+			return r.result(), err
+		}
+	}
+
+	if r.opts.DiscoveryEndCachedCB != nil {
+		err = r.opts.DiscoveryEndCachedCB(discoveredCnt, len(r.opts.Targets), r.opts.totalStats.DiscoveryCached())
+		if err != nil {
+			return r.result(), err
 		}
 	}
 
@@ -180,25 +217,25 @@ func (r *RPC) Do(ctx context.Context, action string, payload interface{}, opts .
 	// the client is always batched, when batched mode is not request the size of
 	// the batch matches the size of the total targets and during setupMessage()
 	// an appropriate connection will be made
-	err = InGroups(r.opts.Targets, r.opts.BatchSize, func(nodes []string) error {
-		r.opts.stats = NewStats()
-		r.opts.stats.SetDiscoveredNodes(nodes)
-		msg.DiscoveredHosts = nodes
+	groups := func(f func([]string) error) error {
+		return InGroups(r.opts.Targets, r.opts.BatchSize, f)
+	}
 
-		r.opts.stats.Start()
-		defer r.opts.totalStats.Merge(r.opts.stats)
-		defer r.opts.stats.End()
+	if r.opts.AdaptiveBatching {
+		groups = r.inAdaptiveGroups
+	}
 
+	err = groups(func(nodes []string) error {
 		if ctr > 0 {
 			err := InterruptableSleep(dctx, r.opts.BatchSleep)
 			if err != nil {
-				return err
+				return fmt.Errorf("%w: %s", ErrRequestCancelled, err)
 			}
 		}
 
 		r.log.Debugf("Performing batched request %d for %d/%d nodes", ctr, len(nodes), len(r.opts.Targets))
 
-		err = r.request(dctx, msg, cl)
+		err = r.requestNodes(dctx, msg, cl, nodes)
 		if err != nil {
 			return err
 		}
@@ -208,19 +245,212 @@ func (r *RPC) Do(ctx context.Context, action string, payload interface{}, opts .
 		return nil
 	})
 
-	r.opts.totalStats.SetAction(action)
+	if err == nil && r.opts.RetryCount > 0 && r.opts.ProcessReplies {
+		err = r.retryUnresponsive(dctx, msg, cl)
+	}
+
+	// replies are not waited for once the context is cancelled so the last batch might have been cut short
+	if err == nil && ctx.Err() != nil && r.opts.ProcessReplies && !r.opts.totalStats.All() {
+		err = fmt.Errorf("%w: %s", ErrRequestCancelled, ctx.Err())
+	}
+
+	return r.result(), err
+}
+
+// result is the outcome of the current request, including when it failed
+func (r *RPC) result() RequestResult {
+	r.opts.totalStats.SetAction(r.opts.action)
 	r.opts.totalStats.SetAgent(r.agent)
 
-	return &RequestOptions{totalStats: r.opts.totalStats}, err
+	return &RequestOptions{totalStats: r.opts.totalStats, batchStats: r.opts.batchStats}
+}
+
+// requestNodes performs the request for a set of nodes as a new batch, the batch stats are kept in the
+// batch history and merged into the total stats
+func (r *RPC) requestNodes(ctx context.Context, msg *choria.Message, cl ChoriaClient, nodes []string) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %s", ErrRequestCancelled, ctx.Err())
+	}
+
+	batch := len(r.opts.batchStats) + 1
+
+	r.opts.stats = NewStats()
+	r.opts.stats.RequestID = msg.RequestID
+	r.opts.stats.SetAgent(r.agent)
+	r.opts.stats.SetAction(r.opts.action)
+	r.opts.stats.SetDiscoveredNodes(nodes)
+	r.opts.stats.RecordAttempt(nodes...)
+	r.opts.batchStats = append(r.opts.batchStats, r.opts.stats)
+	msg.DiscoveredHosts = nodes
+
+	if r.opts.BatchStartCB != nil {
+		r.opts.BatchStartCB(batch, nodes)
+	}
+
+	r.opts.stats.Start()
+	err := r.request(ctx, msg, cl)
+	r.opts.stats.End()
+	r.opts.totalStats.Merge(r.opts.stats)
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: %s", ErrRequestCancelled, err)
+		}
+
+		return fmt.Errorf("%w: %s", ErrPublishFailed, err)
+	}
+
+	if r.opts.BatchEndCB != nil {
+		return r.opts.BatchEndCB(batch, r.opts.stats)
+	}
+
+	return nil
+}
+
+// inAdaptiveGroups calls f with batches of targets, the first batch has BatchSize nodes and the size doubles
+// up to AdaptiveBatchMax after every batch where all nodes responded successfully. When the rate of failed
+// and unresponsive nodes in a batch exceeds AdaptiveBatchThreshold the BatchThresholdCB decides if the
+// rollout continues, without it the rollout is aborted
+func (r *RPC) inAdaptiveGroups(f func([]string) error) error {
+	size := r.opts.BatchSize
+	if size < 1 {
+		size = 1
+	}
+
+	remaining := r.opts.Targets
+
+	for batch := 1; len(remaining) > 0; batch++ {
+		if size > len(remaining) {
+			size = len(remaining)
+		}
+
+		nodes := remaining[:size]
+		remaining = remaining[size:]
+
+		err := f(nodes)
+		if err != nil {
+			return err
+		}
+
+		if len(remaining) == 0 {
+			return nil
+		}
+
+		rate := batchFailureRate(r.opts.stats)
+
+		switch {
+		case rate > r.opts.AdaptiveBatchThreshold:
+			if r.opts.BatchThresholdCB == nil {
+				return fmt.Errorf("aborting after batch %d: %.0f%% of nodes failed or did not respond", batch, rate*100)
+			}
+
+			err = r.opts.BatchThresholdCB(batch, rate, r.opts.stats)
+			if err != nil {
+				return err
+			}
+
+			r.log.Warnf("Continuing after batch %d where %.0f%% of nodes failed or did not respond", batch, rate*100)
+
+		case rate == 0:
+			size = size * 2
+			if r.opts.AdaptiveBatchMax > 0 && size > r.opts.AdaptiveBatchMax {
+				size = r.opts.AdaptiveBatchMax
+			}
+		}
+	}
+
+	return nil
+}
+
+// batchFailureRate is the fraction of nodes in a batch that failed or did not respond
+func batchFailureRate(stats *Stats) float64 {
+	if stats == nil || stats.DiscoveredCount() == 0 {
+		return 0
+	}
+
+	return float64(stats.FailCount()+len(stats.NoResponseFrom())) / float64(stats.DiscoveredCount())
+}
+
+// retryUnresponsive sends the request again to the nodes that did not respond using direct requests,
+// the time between attempts doubles after every attempt. Retries are sent in batches of BatchSize so
+// batched and adaptive requests do not turn into one large request
+func (r *RPC) retryUnresponsive(ctx context.Context, msg *choria.Message, cl ChoriaClient) error {
+	backoff := r.opts.RetryBackoff
+
+	for attempt := 1; attempt <= r.opts.RetryCount; attempt++ {
+		nodes := r.unresponsiveTargets()
+		if len(nodes) == 0 {
+			return nil
+		}
+
+		err := InterruptableSleep(ctx, backoff)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrRequestCancelled, err)
+		}
+
+		backoff = backoff * 2
+
+		if msg.Type() != "direct_request" {
+			err = msg.SetType("direct_request")
+			if err != nil {
+				return err
+			}
+		}
+
+		r.log.Infof("Retrying request %s for %d nodes that did not respond, attempt %d of %d", msg.RequestID, len(nodes), attempt, r.opts.RetryCount)
+
+		size := r.opts.BatchSize
+		if size < 1 {
+			size = len(nodes)
+		}
+
+		ctr := 0
+
+		err = InGroups(nodes, size, func(batch []string) error {
+			if ctr > 0 {
+				err := InterruptableSleep(ctx, r.opts.BatchSleep)
+				if err != nil {
+					return fmt.Errorf("%w: %s", ErrRequestCancelled, err)
+				}
+			}
+
+			ctr++
+
+			return r.requestNodes(ctx, msg, cl, batch)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unresponsiveTargets are the targets that did not respond yet in the order they were targeted
+func (r *RPC) unresponsiveTargets() []string {
+	outstanding := NewNodeList()
+	outstanding.AddHosts(r.opts.totalStats.NoResponseFrom()...)
+
+	nodes := []string{}
+	for _, node := range r.opts.Targets {
+		if outstanding.Have(node) {
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes
 }
 
 func (r *RPC) discover(ctx context.Context) error {
+	d, err := discoverer(r.opts.DiscoveryMethod)
+	if err != nil {
+		return err
+	}
+
 	if r.opts.DiscoveryStartCB != nil {
 		r.opts.DiscoveryStartCB()
 	}
 
-	b := broadcast.New(r.fw)
-
 	r.opts.totalStats.StartDiscover()
 	defer r.opts.totalStats.EndDiscover()
 
@@ -230,7 +460,18 @@ func (r *RPC) discover(ctx context.Context) error {
 
 	r.opts.Filter.AddAgentFilter(r.agent)
 
-	n, err := b.Discover(ctx, broadcast.Filter(r.opts.Filter), broadcast.Timeout(r.opts.DiscoveryTimeout), broadcast.Name(r.opts.ConnectionName), broadcast.Collective(r.opts.Collective))
+	cache, key := r.discoveryCache()
+	if cache != nil && !r.opts.RefreshDiscoveryCache {
+		n, ok := cache.Get(key)
+		if ok && len(n) > 0 {
+			r.opts.totalStats.SetDiscoveryCached(true)
+			r.opts.Targets = n
+
+			return nil
+		}
+	}
+
+	n, err := d.Discover(ctx, r.opts)
 	if err != nil {
 		return err
 	}
@@ -239,15 +480,38 @@ func (r *RPC) discover(ctx context.Context) error {
 		return fmt.Errorf("no targets were discovered")
 	}
 
+	if cache != nil {
+		err = cache.Set(key, n)
+		if err != nil {
+			r.log.Warnf("Could not update the discovery cache: %s", err)
+		}
+	}
+
 	r.opts.Targets = n
 
 	return nil
 }
 
+// discoveryCache is the cache to use for this request and the key to use, nil when caching should not be done
+func (r *RPC) discoveryCache() (*DiscoveryCache, string) {
+	// results discovery depends on a specific previous request so is never cached
+	if r.opts.DiscoveryCache == nil || r.opts.BypassDiscoveryCache || r.opts.DiscoveryMethod == ResultsDiscovery {
+		return nil, ""
+	}
+
+	key, err := DiscoveryCacheKey(r.opts.Filter, r.opts.Collective, r.agent, r.opts.DiscoveryMethod, r.opts.DiscoverySource)
+	if err != nil {
+		r.log.Warnf("Could not use the discovery cache: %s", err)
+		return nil, ""
+	}
+
+	return r.opts.DiscoveryCache, key
+}
+
 func (r *RPC) setupMessage(ctx context.Context, action string, payload interface{}, opts ...RequestOption) (msg *choria.Message, cl ChoriaClient, err error) {
 	pj, err := json.Marshal(payload)
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not encode payload: %s", err)
+		return nil, nil, fmt.Errorf("%w: could not encode payload: %s", ErrInvalidRequest, err)
 	}
 
 	rpcreq := &RPCRequest{
@@ -258,17 +522,17 @@ func (r *RPC) setupMessage(ctx context.Context, action string, payload interface
 
 	rpcp, err := json.Marshal(rpcreq)
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not encode request: %s", err)
+		return nil, nil, fmt.Errorf("%w: could not encode request: %s", ErrInvalidRequest, err)
 	}
 
 	msg, err = r.fw.NewMessage(string(rpcp), r.agent, r.cfg.MainCollective, "request", nil)
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not create Message: %s", err)
+		return nil, nil, fmt.Errorf("%w: could not create Message: %s", ErrInvalidRequest, err)
 	}
 
 	err = r.opts.ConfigureMessage(msg)
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not configure Message: %s", err)
+		return nil, nil, fmt.Errorf("%w: could not configure Message: %s", ErrInvalidRequest, err)
 	}
 
 	cl = r.cl
@@ -276,14 +540,12 @@ func (r *RPC) setupMessage(ctx context.Context, action string, payload interface
 	if r.cl == nil {
 		if r.opts.BatchSize == len(r.opts.Targets) || !r.opts.ProcessReplies {
 			cl, err = r.unbatchedClient()
-			if err != nil {
-				return nil, nil, err
-			}
 		} else {
 			cl, err = r.batchedClient(ctx, msg.RequestID)
-			if err != nil {
-				return nil, nil, err
-			}
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrPublishFailed, err)
 		}
 	}
 
@@ -371,12 +633,20 @@ func (r *RPC) handlerFactory(ctx context.Context, cancel func()) cclient.Handler
 			return
 		}
 
+		if r.opts.ReplyValidation != nil && rpcreply.Statuscode == mcorpc.OK {
+			r.validateReply(reply.SenderID(), rpcreply)
+		}
+
 		if rpcreply.Statuscode == mcorpc.OK {
 			r.opts.stats.PassedRequestInc()
 		} else {
 			r.opts.stats.FailedRequestInc()
 		}
 
+		if len(r.opts.CompatibilityDDLs) > 0 && r.agent == "rpcutil" && r.opts.action == "agent_inventory" && rpcreply.Statuscode == mcorpc.OK {
+			r.checkInventoryCompatibility(reply.SenderID(), rpcreply)
+		}
+
 		if r.opts.Handler != nil {
 			r.opts.Handler(reply, rpcreply)
 		}
@@ -390,6 +660,42 @@ func (r *RPC) handlerFactory(ctx context.Context, cancel func()) cclient.Handler
 	return handler
 }
 
+// validateReply checks the reply data against the DDL outputs, coercing values and
+// marking the reply as failed according to the ReplyValidation option
+func (r *RPC) validateReply(sender string, reply *RPCReply) {
+	actint, err := r.ddl.ActionInterface(r.opts.action)
+	if err != nil {
+		r.log.Warnf("Could not validate reply from %s: %s", sender, err)
+		return
+	}
+
+	result, warnings, err := actint.ValidateReplyJSON(reply.Data, r.opts.ReplyValidation.Coerce)
+	for _, w := range warnings {
+		r.log.Warnf("Validation of the reply from %s returned a warning: %s", sender, w)
+	}
+
+	if err != nil {
+		r.log.Warnf("Reply from %s does not match the DDL: %s", sender, err)
+
+		if r.opts.ReplyValidation.Strict {
+			reply.Statuscode = mcorpc.UnknownError
+			reply.Statusmsg = fmt.Sprintf("Reply does not match the DDL: %s", err)
+		}
+
+		return
+	}
+
+	if r.opts.ReplyValidation.Coerce && len(warnings) > 0 {
+		data, err := json.Marshal(result)
+		if err != nil {
+			r.log.Warnf("Could not encode validated reply from %s: %s", sender, err)
+			return
+		}
+
+		reply.Data = data
+	}
+}
+
 func (r *RPC) connectBatchedConnection(ctx context.Context, name string) (Connector, error) {
 	connector, err := r.fw.NewConnector(ctx, r.fw.MiddlewareServers, name, r.log)
 	if err != nil {
@@ -3,9 +3,11 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	v1 "github.com/choria-io/go-protocol/protocol/v1"
 
@@ -16,7 +18,9 @@ import (
 	"github.com/choria-io/go-choria/choria"
 	client "github.com/choria-io/go-client/client"
 	"github.com/choria-io/go-protocol/protocol"
+	srvcache "github.com/choria-io/go-srvcache"
 	gomock "github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -30,6 +34,15 @@ func TestMcoRPC(t *testing.T) {
 	RunSpecs(t, "McoRPC/Client")
 }
 
+// failingConnectorFramework is a framework that cannot connect to the network
+type failingConnectorFramework struct {
+	ChoriaFramework
+}
+
+func (f *failingConnectorFramework) NewConnector(ctx context.Context, servers func() (srvcache.Servers, error), name string, logger *logrus.Entry) (choria.Connector, error) {
+	return nil, fmt.Errorf("simulated")
+}
+
 var _ = Describe("McoRPC/Client", func() {
 	var (
 		fw      *choria.Framework
@@ -68,6 +81,38 @@ var _ = Describe("McoRPC/Client", func() {
 		mockctl.Finish()
 	})
 
+	// respond sends successful replies from senders to the handler for msg
+	respond := func(ctx context.Context, msg *choria.Message, handler client.Handler, senders ...string) {
+		j, err := json.Marshal(RPCReply{Statusmsg: "OK", Statuscode: mcorpc.OK, Data: json.RawMessage("{}")})
+		Expect(err).ToNot(HaveOccurred())
+
+		mt, err := msg.Transport()
+		Expect(err).ToNot(HaveOccurred())
+
+		sreq, err := fw.NewSecureRequestFromTransport(mt, true)
+		Expect(err).ToNot(HaveOccurred())
+
+		req, err := fw.NewRequestFromSecureRequest(sreq)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, sender := range senders {
+			reply, err := v1.NewReply(req, sender)
+			Expect(err).ToNot(HaveOccurred())
+			reply.SetMessage(string(j))
+
+			srep, err := fw.NewSecureReply(reply)
+			Expect(err).ToNot(HaveOccurred())
+
+			transport, err := fw.NewTransportForSecureReply(srep)
+			Expect(err).ToNot(HaveOccurred())
+
+			tj, err := transport.JSON()
+			Expect(err).ToNot(HaveOccurred())
+
+			handler(ctx, &choria.ConnectorMessage{Data: []byte(tj), Reply: "x", Subject: "x"})
+		}
+	}
+
 	Describe("SetOptions", func() {
 		It("Should set the options", func() {
 			rpc.setOptions()
@@ -109,6 +154,44 @@ var _ = Describe("McoRPC/Client", func() {
 		})
 	})
 
+	Describe("validateReply", func() {
+		BeforeEach(func() {
+			status, err := rpc.ddl.ActionInterface("status")
+			Expect(err).ToNot(HaveOccurred())
+			status.Output["epoch"].Type = "integer"
+
+			rpc.setOptions()
+			rpc.opts.action = "status"
+		})
+
+		It("Should only log invalid replies by default", func() {
+			rpc.opts.ReplyValidation = &agent.ReplyValidation{}
+
+			reply := &RPCReply{Statuscode: mcorpc.OK, Data: json.RawMessage(`{"epoch":"1"}`)}
+			rpc.validateReply("test.sender", reply)
+			Expect(reply.Statuscode).To(Equal(mcorpc.OK))
+			Expect(reply.Data).To(MatchJSON(`{"epoch":"1"}`))
+		})
+
+		It("Should coerce values", func() {
+			ValidateReplies(agent.ReplyValidation{Coerce: true})(rpc.opts)
+
+			reply := &RPCReply{Statuscode: mcorpc.OK, Data: json.RawMessage(`{"epoch":"1"}`)}
+			rpc.validateReply("test.sender", reply)
+			Expect(reply.Statuscode).To(Equal(mcorpc.OK))
+			Expect(reply.Data).To(MatchJSON(`{"epoch":1}`))
+		})
+
+		It("Should fail invalid replies in strict mode", func() {
+			ValidateReplies(agent.ReplyValidation{Strict: true})(rpc.opts)
+
+			reply := &RPCReply{Statuscode: mcorpc.OK, Data: json.RawMessage(`{"epoch":"1"}`)}
+			rpc.validateReply("test.sender", reply)
+			Expect(reply.Statuscode).To(Equal(mcorpc.UnknownError))
+			Expect(reply.Statusmsg).To(Equal("Reply does not match the DDL: output 'epoch' should be of type integer but got string"))
+		})
+	})
+
 	Describe("Do", func() {
 		It("Should perform the request", func() {
 			reqid := ""
@@ -229,6 +312,238 @@ var _ = Describe("McoRPC/Client", func() {
 			Expect(limitedCnt).To(Equal(1))
 		})
 
+		It("Should cache discovery results", func() {
+			cl.EXPECT().Request(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(4).Do(func(ctx context.Context, msg *choria.Message, handler client.Handler) {
+				Expect(msg.DiscoveredHosts).To(Equal([]string{"web1.example.net", "web2.example.net", "db1.example.net"}))
+			})
+
+			cache := NewDiscoveryCache(time.Minute, "")
+			cached := []bool{}
+
+			do := func(opts ...RequestOption) {
+				opts = append(opts,
+					DiscoveryMethod(FlatFileDiscovery),
+					DiscoverySource("testdata/discovery/nodes.txt"),
+					CacheDiscovery(cache),
+					DiscoveryEndCachedCB(func(d, l int, c bool) error {
+						cached = append(cached, c)
+						return nil
+					}),
+				)
+
+				_, err := rpc.Do(ctx, "test_action", request{Testing: true}, opts...)
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			do()
+			do()
+			do(RefreshDiscoveryCache())
+			do(BypassDiscoveryCache())
+
+			Expect(cached).To(Equal([]bool{false, true, false, false}))
+		})
+
+		It("Should retry nodes that did not respond", func() {
+			first := cl.EXPECT().Request(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Do(func(ctx context.Context, msg *choria.Message, handler client.Handler) {
+				Expect(msg.DiscoveredHosts).To(Equal([]string{"host1", "host2", "host3"}))
+				respond(ctx, msg, handler, "host1")
+			})
+
+			second := cl.EXPECT().Request(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).After(first).Do(func(ctx context.Context, msg *choria.Message, handler client.Handler) {
+				Expect(msg.DiscoveredHosts).To(Equal([]string{"host2", "host3"}))
+				Expect(msg.Type()).To(Equal("direct_request"))
+				respond(ctx, msg, handler, "host2")
+			})
+
+			cl.EXPECT().Request(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).After(second).Do(func(ctx context.Context, msg *choria.Message, handler client.Handler) {
+				Expect(msg.DiscoveredHosts).To(Equal([]string{"host3"}))
+			})
+
+			result, err := rpc.Do(ctx, "test_action", request{Testing: true},
+				Targets([]string{"host1", "host2", "host3"}),
+				RetryUnresponsive(2, time.Millisecond),
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			stats := result.Stats()
+			Expect(stats.NoResponseFrom()).To(Equal([]string{"host3"}))
+			Expect(stats.OKCount()).To(Equal(2))
+			Expect(stats.NodeAttempts()).To(Equal(map[string]int{"host1": 1, "host2": 2, "host3": 3}))
+		})
+
+		It("Should retry batched requests in batches", func() {
+			batches := [][]string{}
+
+			cl.EXPECT().Request(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(4).Do(func(ctx context.Context, msg *choria.Message, handler client.Handler) {
+				batches = append(batches, msg.DiscoveredHosts)
+				if len(batches) == 1 {
+					respond(ctx, msg, handler, "host1")
+				}
+			})
+
+			_, err := rpc.Do(ctx, "test_action", request{Testing: true},
+				Targets(strings.Fields("host1 host2 host3 host4")),
+				InBatches(2, 0),
+				RetryUnresponsive(1, time.Millisecond),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(batches).To(Equal([][]string{{"host1", "host2"}, {"host3", "host4"}, {"host2", "host3"}, {"host4"}}))
+		})
+
+		It("Should support batch callbacks and record batch stats", func() {
+			cl.EXPECT().Request(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2).Do(func(ctx context.Context, msg *choria.Message, handler client.Handler) {
+				respond(ctx, msg, handler, msg.DiscoveredHosts[0])
+			})
+
+			started := map[int][]string{}
+			ended := []int{}
+
+			result, err := rpc.Do(ctx, "test_action", request{Testing: true},
+				Targets(strings.Fields("host1 host2 host3 host4 host5 host6")),
+				InBatches(2, 0),
+				BatchStartCB(func(batch int, nodes []string) {
+					started[batch] = nodes
+				}),
+				BatchEndCB(func(batch int, stats *Stats) error {
+					ended = append(ended, batch)
+					Expect(stats.OKCount()).To(Equal(1))
+					Expect(stats.NoResponseFrom()).To(Equal(started[batch][1:]))
+
+					if batch == 2 {
+						return fmt.Errorf("simulated")
+					}
+
+					return nil
+				}),
+			)
+			Expect(err).To(MatchError("simulated"))
+			Expect(started).To(Equal(map[int][]string{1: {"host1", "host2"}, 2: {"host3", "host4"}}))
+			Expect(ended).To(Equal([]int{1, 2}))
+
+			batches := result.BatchStats()
+			Expect(batches).To(HaveLen(2))
+			Expect(*batches[0].DiscoveredNodes()).To(Equal([]string{"host1", "host2"}))
+			Expect(*batches[1].DiscoveredNodes()).To(Equal([]string{"host3", "host4"}))
+			Expect(batches[1].Action()).To(Equal("test_action"))
+			Expect(batches[1].Agent()).To(Equal("package"))
+			Expect(result.Stats().OKCount()).To(Equal(2))
+		})
+
+		It("Should return partial results when cancelled", func() {
+			cl.EXPECT().Request(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1).Do(func(rctx context.Context, msg *choria.Message, handler client.Handler) {
+				respond(rctx, msg, handler, msg.DiscoveredHosts...)
+				cancel()
+			})
+
+			result, err := rpc.Do(ctx, "test_action", request{Testing: true},
+				Targets(strings.Fields("host1 host2 host3 host4 host5 host6")),
+				InBatches(2, 0),
+			)
+			Expect(errors.Is(err, ErrRequestCancelled)).To(BeTrue())
+			Expect(result.BatchStats()).To(HaveLen(1))
+			Expect(result.Stats().OKCount()).To(Equal(2))
+			Expect(result.Stats().UncontactedNodes()).To(Equal([]string{"host3", "host4", "host5", "host6"}))
+		})
+
+		It("Should return partial results when publishing fails", func() {
+			first := cl.EXPECT().Request(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			cl.EXPECT().Request(gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("simulated")).After(first)
+
+			result, err := rpc.Do(ctx, "test_action", request{Testing: true},
+				Targets(strings.Fields("host1 host2 host3 host4 host5 host6")),
+				InBatches(2, 0),
+			)
+			Expect(errors.Is(err, ErrPublishFailed)).To(BeTrue())
+			Expect(err).To(MatchError("publish failed: simulated"))
+			Expect(result.BatchStats()).To(HaveLen(2))
+			Expect(result.Stats().UncontactedNodes()).To(Equal([]string{"host5", "host6"}))
+		})
+
+		It("Should detect invalid requests", func() {
+			_, err := rpc.Do(ctx, "test_action", make(chan int), Targets([]string{"host1"}))
+			Expect(errors.Is(err, ErrInvalidRequest)).To(BeTrue())
+			Expect(err).To(MatchError(HavePrefix("could not configure message: invalid request: could not encode payload: ")))
+
+			_, err = rpc.Do(ctx, "test_action", request{Testing: true}, Targets(strings.Fields("host1 host2")), InBatches(1, 0), BroadcastRequest())
+			Expect(errors.Is(err, ErrInvalidRequest)).To(BeTrue())
+			Expect(err).To(MatchError("could not configure message: invalid request: could not configure Message: batched mode requires direct_request mode"))
+		})
+
+		It("Should detect batched connection failures", func() {
+			rpc, err = New(&failingConnectorFramework{fw}, "package")
+			Expect(err).ToNot(HaveOccurred())
+
+			result, err := rpc.Do(ctx, "test_action", request{Testing: true}, Targets(strings.Fields("host1 host2")), InBatches(1, 0))
+			Expect(errors.Is(err, ErrPublishFailed)).To(BeTrue())
+			Expect(err).To(MatchError("could not configure message: publish failed: could not connect batched network connection: could not create connector: simulated"))
+			Expect(result.Stats().UncontactedNodes()).To(Equal([]string{"host1", "host2"}))
+		})
+
+		It("Should return results when discovery fails", func() {
+			result, err := rpc.Do(ctx, "test_action", request{Testing: true}, DiscoveryMethod(FlatFileDiscovery))
+			Expect(errors.Is(err, ErrDiscoveryFailed)).To(BeTrue())
+			Expect(err).To(MatchError("discovery failed: no discovery source file set"))
+			Expect(result.Stats().Action()).To(Equal("test_action"))
+			Expect(result.BatchStats()).To(BeEmpty())
+		})
+
+		It("Should grow adaptive batches while they succeed", func() {
+			batches := [][]string{}
+
+			cl.EXPECT().Request(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(5).Do(func(ctx context.Context, msg *choria.Message, handler client.Handler) {
+				batches = append(batches, msg.DiscoveredHosts)
+				respond(ctx, msg, handler, msg.DiscoveredHosts...)
+			})
+
+			_, err := rpc.Do(ctx, "test_action", request{Testing: true},
+				Targets(strings.Fields("host1 host2 host3 host4 host5 host6 host7 host8 host9 host10")),
+				InAdaptiveBatches(1, 3, 0, 0),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(batches).To(Equal([][]string{
+				{"host1"},
+				{"host2", "host3"},
+				{"host4", "host5", "host6"},
+				{"host7", "host8", "host9"},
+				{"host10"},
+			}))
+		})
+
+		It("Should abort adaptive batches that exceed the threshold", func() {
+			cl.EXPECT().Request(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2).Do(func(ctx context.Context, msg *choria.Message, handler client.Handler) {
+				respond(ctx, msg, handler, msg.DiscoveredHosts[1:]...)
+			})
+
+			called := []int{}
+			_, err := rpc.Do(ctx, "test_action", request{Testing: true},
+				Targets(strings.Fields("host1 host2 host3 host4 host5 host6 host7 host8")),
+				InAdaptiveBatches(2, 0, 0.25, 0),
+				BatchThresholdCB(func(batch int, rate float64, stats *Stats) error {
+					Expect(rate).To(Equal(0.5))
+					Expect(stats.NoResponseFrom()).To(HaveLen(1))
+					called = append(called, batch)
+
+					if batch == 2 {
+						return fmt.Errorf("simulated")
+					}
+
+					return nil
+				}),
+			)
+			Expect(err).To(MatchError("simulated"))
+			Expect(called).To(Equal([]int{1, 2}))
+
+			cl.EXPECT().Request(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1).Do(func(ctx context.Context, msg *choria.Message, handler client.Handler) {
+				Expect(msg.DiscoveredHosts).To(Equal([]string{"host1", "host2"}))
+			})
+
+			_, err = rpc.Do(ctx, "test_action", request{Testing: true},
+				Targets(strings.Fields("host1 host2 host3 host4")),
+				InAdaptiveBatches(2, 0, 0.5, 0),
+			)
+			Expect(err).To(MatchError("aborting after batch 1: 100% of nodes failed or did not respond"))
+		})
+
 		It("Should interruptable by the discovery callback", func() {
 			_, err := rpc.Do(ctx, "test_action", request{Testing: true},
 				Targets([]string{"host1", "host2", "host3", "host4"}),
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	addl "github.com/choria-io/mcorpc-agent-provider/mcorpc/ddl/agent"
+)
+
+// AgentCompatibility is the compatibility of an agent deployed on a node with the DDL used by the client
+type AgentCompatibility struct {
+	Agent   string
+	Version string
+
+	*addl.Compatibility
+}
+
+// InventoryCompatibility compares the agent versions found in a rpcutil#agent_inventory reply
+// with the given DDLs, agents not found on the node are not included
+func InventoryCompatibility(reply *RPCReply, ddls ...*addl.DDL) ([]AgentCompatibility, error) {
+	inventory := struct {
+		Agents []struct {
+			Agent   string `json:"agent"`
+			Version string `json:"version"`
+		} `json:"agents"`
+	}{}
+
+	err := json.Unmarshal(reply.Data, &inventory)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode agent inventory: %s", err)
+	}
+
+	result := []AgentCompatibility{}
+
+	for _, ddl := range ddls {
+		for _, agent := range inventory.Agents {
+			if agent.Agent != ddl.Metadata.Name {
+				continue
+			}
+
+			result = append(result, AgentCompatibility{
+				Agent:         agent.Agent,
+				Version:       agent.Version,
+				Compatibility: ddl.CompareVersion(agent.Version),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+func (r *RPC) checkInventoryCompatibility(sender string, reply *RPCReply) {
+	compats, err := InventoryCompatibility(reply, r.opts.CompatibilityDDLs...)
+	if err != nil {
+		r.log.Warnf("Could not check agent compatibility of %s: %s", sender, err)
+		return
+	}
+
+	for _, compat := range compats {
+		if compat.Unknown != "" {
+			r.log.Debugf("Could not check compatibility of version %s of the %s agent on %s: %s", compat.Version, compat.Agent, sender, compat.Unknown)
+			continue
+		}
+
+		if compat.Compatible() {
+			continue
+		}
+
+		changes := []string{}
+		for _, change := range compat.Breaking() {
+			changes = append(changes, change.Description)
+		}
+
+		r.log.Warnf("%s runs version %s of the %s agent which is incompatible with the client DDL: %s", sender, compat.Version, compat.Agent, strings.Join(changes, ", "))
+
+		if r.opts.IncompatibleAgentCB != nil {
+			r.opts.IncompatibleAgentCB(sender, compat.Agent, compat.Version, compat.Compatibility)
+		}
+	}
+}
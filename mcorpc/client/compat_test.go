@@ -0,0 +1,75 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/choria-io/go-choria/choria"
+	"github.com/choria-io/mcorpc-agent-provider/mcorpc"
+	"github.com/choria-io/mcorpc-agent-provider/mcorpc/ddl/agent"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("McoRPC/Client/Compatibility", func() {
+	var (
+		pkg   *agent.DDL
+		reply *RPCReply
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		pkg, err = agent.Find("package", []string{"testdata"})
+		Expect(err).ToNot(HaveOccurred())
+
+		reply = &RPCReply{
+			Statuscode: mcorpc.OK,
+			Data:       json.RawMessage(`{"agents":[{"agent":"package","version":"6.0.0"},{"agent":"rpcutil","version":"1.0.0"}]}`),
+		}
+	})
+
+	Describe("InventoryCompatibility", func() {
+		It("Should compare the versions of known agents", func() {
+			compat, err := InventoryCompatibility(reply, pkg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(compat).To(HaveLen(1))
+			Expect(compat[0].Agent).To(Equal("package"))
+			Expect(compat[0].Version).To(Equal("6.0.0"))
+			Expect(compat[0].Compatible()).To(BeFalse())
+		})
+
+		It("Should handle invalid replies", func() {
+			reply.Data = json.RawMessage(`[]`)
+			_, err := InventoryCompatibility(reply, pkg)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("checkInventoryCompatibility", func() {
+		It("Should notify about incompatible agents", func() {
+			fw, _ := choria.New("testdata/default.cfg")
+			rpc, err := New(fw, "package")
+			Expect(err).ToNot(HaveOccurred())
+
+			var incompatible []string
+
+			err = rpc.setOptions(WarnIncompatibleAgents(pkg), IncompatibleAgentCB(func(sender string, agent string, version string, compat *agent.Compatibility) {
+				incompatible = append(incompatible, sender, agent, version, compat.Breaking()[0].Description)
+			}))
+			Expect(err).ToNot(HaveOccurred())
+
+			rpc.checkInventoryCompatibility("node1", reply)
+			Expect(incompatible).To(Equal([]string{"node1", "package", "6.0.0", "major version changed from 5.0.0 to 6.0.0"}))
+
+			incompatible = nil
+			reply.Data = json.RawMessage(`{"agents":[{"agent":"package","version":"5.1.0"}]}`)
+			rpc.checkInventoryCompatibility("node1", reply)
+			Expect(incompatible).To(BeEmpty())
+
+			reply.Data = json.RawMessage(`{"agents":[{"agent":"package","version":"5.x"}]}`)
+			rpc.checkInventoryCompatibility("node1", reply)
+			Expect(incompatible).To(BeEmpty())
+		})
+	})
+})
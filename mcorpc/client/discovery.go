@@ -0,0 +1,345 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/choria-io/go-client/discovery/broadcast"
+	"github.com/choria-io/go-protocol/filter/agents"
+	"github.com/choria-io/go-protocol/protocol"
+	"github.com/ghodss/yaml"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// BroadcastDiscovery discovers nodes by sending a discovery request to the network
+	BroadcastDiscovery = "broadcast"
+
+	// FlatFileDiscovery reads node identities from the file set using DiscoverySource, one per line
+	FlatFileDiscovery = "flatfile"
+
+	// InventoryDiscovery reads nodes with their facts, classes and agents from a JSON or YAML file set using DiscoverySource
+	InventoryDiscovery = "inventory"
+
+	// ResultsDiscovery targets the nodes that responded to a previous request set using DiscoveryResults
+	ResultsDiscovery = "results"
+)
+
+// Discoverer finds the nodes matching the filter in the request options, the filter
+// always includes an agent filter for the agent being requested
+type Discoverer interface {
+	Discover(ctx context.Context, opts *RequestOptions) ([]string, error)
+}
+
+// DiscovererFunc is a function that implements Discoverer
+type DiscovererFunc func(ctx context.Context, opts *RequestOptions) ([]string, error)
+
+// Discover implements Discoverer
+func (f DiscovererFunc) Discover(ctx context.Context, opts *RequestOptions) ([]string, error) {
+	return f(ctx, opts)
+}
+
+// Inventory is the format of the files used by the inventory discovery method
+type Inventory struct {
+	Nodes []InventoryNode `json:"nodes"`
+}
+
+// InventoryNode is a node found in an inventory file
+type InventoryNode struct {
+	Identity string          `json:"identity"`
+	Agents   []string        `json:"agents,omitempty"`
+	Classes  []string        `json:"classes,omitempty"`
+	Facts    json.RawMessage `json:"facts,omitempty"`
+}
+
+var (
+	discoverers = map[string]Discoverer{
+		BroadcastDiscovery: DiscovererFunc(broadcastDiscover),
+		FlatFileDiscovery:  DiscovererFunc(flatFileDiscover),
+		InventoryDiscovery: DiscovererFunc(inventoryDiscover),
+		ResultsDiscovery:   DiscovererFunc(resultsDiscover),
+	}
+
+	discoverersMu = &sync.Mutex{}
+)
+
+// RegisterDiscoveryMethod adds a discovery method that can be selected using the DiscoveryMethod() option
+func RegisterDiscoveryMethod(name string, d Discoverer) error {
+	discoverersMu.Lock()
+	defer discoverersMu.Unlock()
+
+	if _, ok := discoverers[name]; ok {
+		return fmt.Errorf("a discovery method called '%s' is already registered", name)
+	}
+
+	discoverers[name] = d
+
+	return nil
+}
+
+// DiscoveryMethods is the sorted list of known discovery methods
+func DiscoveryMethods() []string {
+	discoverersMu.Lock()
+	defer discoverersMu.Unlock()
+
+	methods := []string{}
+	for name := range discoverers {
+		methods = append(methods, name)
+	}
+
+	sort.Strings(methods)
+
+	return methods
+}
+
+func discoverer(name string) (Discoverer, error) {
+	discoverersMu.Lock()
+	defer discoverersMu.Unlock()
+
+	d, ok := discoverers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown discovery method '%s'", name)
+	}
+
+	return d, nil
+}
+
+func broadcastDiscover(ctx context.Context, opts *RequestOptions) ([]string, error) {
+	b := broadcast.New(opts.fw)
+
+	return b.Discover(ctx, broadcast.Filter(opts.Filter), broadcast.Timeout(opts.DiscoveryTimeout), broadcast.Name(opts.ConnectionName), broadcast.Collective(opts.Collective))
+}
+
+// flatFileDiscover reads identities from a file, only identity filters can be applied to them
+func flatFileDiscover(ctx context.Context, opts *RequestOptions) ([]string, error) {
+	if opts.DiscoverySource == "" {
+		return nil, fmt.Errorf("no discovery source file set")
+	}
+
+	err := identityFiltersOnly(FlatFileDiscovery, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(opts.DiscoverySource)
+	if err != nil {
+		return nil, fmt.Errorf("could not read discovery source: %s", err)
+	}
+	defer file.Close()
+
+	nodes := []string{}
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if len(opts.Filter.Identity) > 0 && !opts.Filter.MatchIdentity(line) {
+			continue
+		}
+
+		nodes = append(nodes, line)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not read discovery source: %s", err)
+	}
+
+	return uniqueNodes(nodes), nil
+}
+
+// inventoryDiscover matches nodes in an inventory file, agent filters are only applied to nodes that list their agents
+func inventoryDiscover(ctx context.Context, opts *RequestOptions) ([]string, error) {
+	if opts.DiscoverySource == "" {
+		return nil, fmt.Errorf("no discovery source file set")
+	}
+
+	if len(opts.Filter.Compound) > 0 {
+		return nil, fmt.Errorf("the %s discovery method does not support compound filters", InventoryDiscovery)
+	}
+
+	dat, err := ioutil.ReadFile(opts.DiscoverySource)
+	if err != nil {
+		return nil, fmt.Errorf("could not read discovery source: %s", err)
+	}
+
+	inventory := &Inventory{}
+	err = yaml.Unmarshal(dat, inventory)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse inventory %s: %s", opts.DiscoverySource, err)
+	}
+
+	nodes := []string{}
+
+	for _, node := range inventory.Nodes {
+		matched, err := node.Match(opts.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("could not match node %s: %s", node.Identity, err)
+		}
+
+		if matched {
+			nodes = append(nodes, node.Identity)
+		}
+	}
+
+	return uniqueNodes(nodes), nil
+}
+
+// resultsDiscover targets the nodes that responded to a previous request, only identity filters can be applied to them
+func resultsDiscover(ctx context.Context, opts *RequestOptions) ([]string, error) {
+	if opts.DiscoveryResults == nil {
+		return nil, fmt.Errorf("no previous request results set")
+	}
+
+	err := identityFiltersOnly(ResultsDiscovery, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := opts.DiscoveryResults.Stats()
+	missing := NewNodeList()
+	missing.AddHosts(stats.NoResponseFrom()...)
+
+	nodes := []string{}
+	for _, node := range *stats.DiscoveredNodes() {
+		if missing.Have(node) {
+			continue
+		}
+
+		if len(opts.Filter.Identity) > 0 && !opts.Filter.MatchIdentity(node) {
+			continue
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return uniqueNodes(nodes), nil
+}
+
+// identityFiltersOnly fails for filters that discovery methods without node facts or classes cannot apply
+func identityFiltersOnly(method string, filter *protocol.Filter) error {
+	if len(filter.Fact) > 0 || len(filter.Class) > 0 || len(filter.Compound) > 0 {
+		return fmt.Errorf("the %s discovery method only supports identity filters", method)
+	}
+
+	return nil
+}
+
+// Match determines if the node matches all the identity, agent, class and fact filters
+func (n *InventoryNode) Match(filter *protocol.Filter) (bool, error) {
+	if n.Identity == "" {
+		return false, nil
+	}
+
+	if len(filter.Identity) > 0 && !filter.MatchIdentity(n.Identity) {
+		return false, nil
+	}
+
+	if len(filter.Agent) > 0 && len(n.Agents) > 0 && !filter.MatchAgents(n.Agents) {
+		return false, nil
+	}
+
+	// classes are matched with the same rules as agents
+	if len(filter.Class) > 0 && !agents.Match(filter.Class, n.Classes) {
+		return false, nil
+	}
+
+	for _, f := range filter.Fact {
+		matched, err := matchFact(n.Facts, f.Fact, f.Operator, f.Value)
+		if err != nil {
+			return false, err
+		}
+
+		if !matched {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// matchFact compares a fact numerically when both sides are numbers and as strings otherwise
+func matchFact(facts json.RawMessage, fact string, operator string, value string) (bool, error) {
+	found := gjson.GetBytes(facts, fact)
+	if !found.Exists() {
+		return false, nil
+	}
+
+	if operator == "=~" {
+		re, err := regexp.Compile(strings.TrimSuffix(strings.TrimPrefix(value, "/"), "/"))
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression %s: %s", value, err)
+		}
+
+		return re.MatchString(found.String()), nil
+	}
+
+	cmp := strings.Compare(found.String(), value)
+
+	switch found.Type {
+	case gjson.Number:
+		v, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			switch {
+			case found.Float() < v:
+				cmp = -1
+			case found.Float() > v:
+				cmp = 1
+			default:
+				cmp = 0
+			}
+		}
+
+	case gjson.True, gjson.False:
+		v, err := strconv.ParseBool(value)
+		if err == nil && v == found.Bool() {
+			cmp = 0
+		}
+	}
+
+	switch operator {
+	case "==":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case ">":
+		return cmp > 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	default:
+		return false, fmt.Errorf("unknown fact matching operator %s while looking for fact %s", operator, fact)
+	}
+}
+
+// uniqueNodes removes duplicates while keeping the order of nodes
+func uniqueNodes(nodes []string) []string {
+	seen := make(map[string]struct{})
+	unique := []string{}
+
+	for _, node := range nodes {
+		if _, ok := seen[node]; ok {
+			continue
+		}
+
+		seen[node] = struct{}{}
+		unique = append(unique, node)
+	}
+
+	return unique
+}
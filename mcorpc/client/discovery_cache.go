@@ -0,0 +1,206 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/choria-io/go-protocol/protocol"
+)
+
+// DiscoveryCache stores discovered nodes for a period of time so that repeated requests using the same
+// filter, collective and agent do not have to perform discovery again. Entries are kept in memory and
+// when a directory is set also on disk so they can be shared between processes
+type DiscoveryCache struct {
+	ttl     time.Duration
+	dir     string
+	entries map[string]*discoveryCacheEntry
+
+	sync.Mutex
+}
+
+type discoveryCacheEntry struct {
+	Created time.Time `json:"created"`
+	Nodes   []string  `json:"nodes"`
+}
+
+// NewDiscoveryCache creates a cache that keeps discovery results for ttl, an empty dir keeps results in memory only
+func NewDiscoveryCache(ttl time.Duration, dir string) *DiscoveryCache {
+	return &DiscoveryCache{
+		ttl:     ttl,
+		dir:     dir,
+		entries: make(map[string]*discoveryCacheEntry),
+	}
+}
+
+// DefaultDiscoveryCacheDir is the directory in the users home directory where discovery results are stored
+func DefaultDiscoveryCacheDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %s", err)
+	}
+
+	return filepath.Join(home, ".choria", "discovery"), nil
+}
+
+// TTL is the time discovery results are considered valid
+func (c *DiscoveryCache) TTL() time.Duration {
+	return c.ttl
+}
+
+// Get retrieves the cached nodes for a key, false when the key is unknown or expired
+func (c *DiscoveryCache) Get(key string) ([]string, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, ok := c.entries[key]
+
+	// another process might have stored a newer entry on disk
+	if (!ok || c.expired(entry)) && c.dir != "" {
+		entry, ok = c.load(key)
+	}
+
+	if !ok {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	if c.expired(entry) {
+		c.delete(key)
+		return nil, false
+	}
+
+	c.entries[key] = entry
+
+	return append([]string{}, entry.Nodes...), true
+}
+
+// Set stores the nodes discovered for a key
+func (c *DiscoveryCache) Set(key string, nodes []string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	entry := &discoveryCacheEntry{
+		Created: time.Now(),
+		Nodes:   append([]string{}, nodes...),
+	}
+
+	c.entries[key] = entry
+
+	if c.dir == "" {
+		return nil
+	}
+
+	err := os.MkdirAll(c.dir, 0700)
+	if err != nil {
+		return fmt.Errorf("could not create discovery cache directory: %s", err)
+	}
+
+	j, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not encode discovery cache entry: %s", err)
+	}
+
+	// write to a unique temporary file and rename it so other processes never read partial entries
+	tmp, err := ioutil.TempFile(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not create discovery cache entry: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(j)
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write discovery cache entry: %s", err)
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return fmt.Errorf("could not write discovery cache entry: %s", err)
+	}
+
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+// Clear removes all entries from the cache
+func (c *DiscoveryCache) Clear() error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.entries = make(map[string]*discoveryCacheEntry)
+
+	if c.dir == "" {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		err = os.Remove(file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *DiscoveryCache) load(key string) (*discoveryCacheEntry, bool) {
+	j, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	entry := &discoveryCacheEntry{}
+	err = json.Unmarshal(j, entry)
+	if err != nil {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+func (c *DiscoveryCache) expired(entry *discoveryCacheEntry) bool {
+	return time.Since(entry.Created) > c.ttl
+}
+
+// delete removes an expired entry, when a directory is set the entry was loaded from disk
+// and found to be expired so the file is removed too
+func (c *DiscoveryCache) delete(key string) {
+	delete(c.entries, key)
+
+	if c.dir != "" {
+		os.Remove(c.path(key))
+	}
+}
+
+func (c *DiscoveryCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// DiscoveryCacheKey is the cache key for discovering nodes with agent in collective using a filter, method and source
+func DiscoveryCacheKey(filter *protocol.Filter, collective string, agent string, method string, source string) (string, error) {
+	if filter == nil {
+		filter = protocol.NewFilter()
+	}
+
+	f, err := json.Marshal(filter)
+	if err != nil {
+		return "", fmt.Errorf("could not encode filter: %s", err)
+	}
+
+	j, err := json.Marshal([]string{string(f), collective, agent, method, source})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(j)), nil
+}
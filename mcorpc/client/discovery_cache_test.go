@@ -0,0 +1,109 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/choria-io/go-protocol/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("McoRPC/Client/DiscoveryCache", func() {
+	var (
+		dir string
+		err error
+	)
+
+	BeforeEach(func() {
+		dir, err = ioutil.TempDir("", "")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Describe("Get", func() {
+		It("Should expire entries", func() {
+			cache := NewDiscoveryCache(time.Minute, "")
+			Expect(cache.Set("key", []string{"node1"})).To(Succeed())
+
+			nodes, ok := cache.Get("key")
+			Expect(ok).To(BeTrue())
+			Expect(nodes).To(Equal([]string{"node1"}))
+
+			cache.entries["key"].Created = time.Now().Add(-2 * time.Minute)
+			_, ok = cache.Get("key")
+			Expect(ok).To(BeFalse())
+			Expect(cache.entries).To(BeEmpty())
+		})
+
+		It("Should share entries on disk", func() {
+			Expect(NewDiscoveryCache(time.Minute, dir).Set("key", []string{"node1", "node2"})).To(Succeed())
+			Expect(filepath.Join(dir, "key.json")).To(BeARegularFile())
+
+			nodes, ok := NewDiscoveryCache(time.Minute, dir).Get("key")
+			Expect(ok).To(BeTrue())
+			Expect(nodes).To(Equal([]string{"node1", "node2"}))
+
+			_, ok = NewDiscoveryCache(time.Duration(0), dir).Get("key")
+			Expect(ok).To(BeFalse())
+			Expect(filepath.Join(dir, "key.json")).ToNot(BeAnExistingFile())
+		})
+
+		It("Should reload expired entries from disk", func() {
+			cache := NewDiscoveryCache(time.Minute, dir)
+			Expect(cache.Set("key", []string{"node1"})).To(Succeed())
+			cache.entries["key"].Created = time.Now().Add(-2 * time.Minute)
+
+			Expect(NewDiscoveryCache(time.Minute, dir).Set("key", []string{"node2"})).To(Succeed())
+
+			nodes, ok := cache.Get("key")
+			Expect(ok).To(BeTrue())
+			Expect(nodes).To(Equal([]string{"node2"}))
+			Expect(filepath.Join(dir, "key.json")).To(BeARegularFile())
+		})
+	})
+
+	Describe("Set", func() {
+		It("Should not leave temporary files behind", func() {
+			Expect(NewDiscoveryCache(time.Minute, dir).Set("key", []string{"node1"})).To(Succeed())
+			Expect(NewDiscoveryCache(time.Minute, dir).Set("key", []string{"node2"})).To(Succeed())
+
+			files, err := ioutil.ReadDir(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			Expect(files[0].Name()).To(Equal("key.json"))
+		})
+	})
+
+	Describe("Clear", func() {
+		It("Should remove all entries", func() {
+			cache := NewDiscoveryCache(time.Minute, dir)
+			Expect(cache.Set("key", []string{"node1"})).To(Succeed())
+			Expect(cache.Clear()).To(Succeed())
+
+			_, ok := cache.Get("key")
+			Expect(ok).To(BeFalse())
+			Expect(filepath.Join(dir, "key.json")).ToNot(BeAnExistingFile())
+		})
+	})
+
+	Describe("DiscoveryCacheKey", func() {
+		It("Should include the filter, collective and agent", func() {
+			f := protocol.NewFilter()
+			f.AddClassFilter("role::web")
+
+			key, err := DiscoveryCacheKey(f, "mcollective", "package", BroadcastDiscovery, "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(DiscoveryCacheKey(f, "mcollective", "package", BroadcastDiscovery, "")).To(Equal(key))
+			Expect(DiscoveryCacheKey(f, "other", "package", BroadcastDiscovery, "")).ToNot(Equal(key))
+			Expect(DiscoveryCacheKey(f, "mcollective", "service", BroadcastDiscovery, "")).ToNot(Equal(key))
+			Expect(DiscoveryCacheKey(protocol.NewFilter(), "mcollective", "package", BroadcastDiscovery, "")).ToNot(Equal(key))
+		})
+	})
+})
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+
+	"github.com/choria-io/go-protocol/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("McoRPC/Client/Discovery", func() {
+	var (
+		opts *RequestOptions
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		opts = &RequestOptions{Filter: protocol.NewFilter()}
+		opts.Filter.AddAgentFilter("package")
+		ctx = context.Background()
+	})
+
+	Describe("RegisterDiscoveryMethod", func() {
+		It("Should register new methods only once", func() {
+			defer func() {
+				discoverersMu.Lock()
+				delete(discoverers, "test_method")
+				discoverersMu.Unlock()
+			}()
+
+			d := DiscovererFunc(func(_ context.Context, _ *RequestOptions) ([]string, error) {
+				return []string{"test.example.net"}, nil
+			})
+
+			Expect(RegisterDiscoveryMethod("test_method", d)).To(Succeed())
+			Expect(RegisterDiscoveryMethod("test_method", d)).To(MatchError("a discovery method called 'test_method' is already registered"))
+			Expect(DiscoveryMethods()).To(ContainElement("test_method"))
+
+			found, err := discoverer("test_method")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found.Discover(ctx, opts)).To(Equal([]string{"test.example.net"}))
+		})
+
+		It("Should fail for unknown methods", func() {
+			_, err := discoverer("unknown")
+			Expect(err).To(MatchError("unknown discovery method 'unknown'"))
+		})
+	})
+
+	Describe("flatFileDiscover", func() {
+		It("Should require a source", func() {
+			_, err := flatFileDiscover(ctx, opts)
+			Expect(err).To(MatchError("no discovery source file set"))
+		})
+
+		It("Should only support identity filters", func() {
+			opts.DiscoverySource = "testdata/discovery/nodes.txt"
+			opts.Filter.AddClassFilter("role::web")
+			_, err := flatFileDiscover(ctx, opts)
+			Expect(err).To(MatchError("the flatfile discovery method only supports identity filters"))
+		})
+
+		It("Should read unique nodes and apply identity filters", func() {
+			opts.DiscoverySource = "testdata/discovery/nodes.txt"
+			Expect(flatFileDiscover(ctx, opts)).To(Equal([]string{"web1.example.net", "web2.example.net", "db1.example.net"}))
+
+			opts.Filter.AddIdentityFilter("/^web/")
+			Expect(flatFileDiscover(ctx, opts)).To(Equal([]string{"web1.example.net", "web2.example.net"}))
+		})
+	})
+
+	Describe("inventoryDiscover", func() {
+		BeforeEach(func() {
+			opts.DiscoverySource = "testdata/discovery/inventory.yaml"
+		})
+
+		It("Should only match agents on nodes that list them", func() {
+			Expect(inventoryDiscover(ctx, opts)).To(Equal([]string{"web1.example.net", "web2.example.net"}))
+		})
+
+		It("Should match classes and identities", func() {
+			opts.Filter = protocol.NewFilter()
+			opts.Filter.AddClassFilter("/^role::/")
+			opts.Filter.AddIdentityFilter("db1.example.net")
+			Expect(inventoryDiscover(ctx, opts)).To(Equal([]string{"db1.example.net"}))
+		})
+
+		It("Should match facts", func() {
+			opts.Filter = protocol.NewFilter()
+			Expect(opts.Filter.AddFactFilter("os.family", "==", "RedHat")).To(Succeed())
+			Expect(opts.Filter.AddFactFilter("memory", ">=", "8192")).To(Succeed())
+			Expect(inventoryDiscover(ctx, opts)).To(Equal([]string{"db1.example.net"}))
+
+			opts.Filter = protocol.NewFilter()
+			Expect(opts.Filter.AddFactFilter("virtual", "==", "false")).To(Succeed())
+			Expect(inventoryDiscover(ctx, opts)).To(Equal([]string{"web2.example.net"}))
+
+			opts.Filter = protocol.NewFilter()
+			Expect(opts.Filter.AddFactFilter("os.family", "=~", "/^Deb/")).To(Succeed())
+			Expect(inventoryDiscover(ctx, opts)).To(Equal([]string{"web2.example.net"}))
+		})
+
+		It("Should not support compound filters", func() {
+			Expect(opts.Filter.AddCompoundFilter(`[{"statement": "role::web"}]`)).To(Succeed())
+			_, err := inventoryDiscover(ctx, opts)
+			Expect(err).To(MatchError("the inventory discovery method does not support compound filters"))
+		})
+	})
+
+	Describe("resultsDiscover", func() {
+		It("Should require results", func() {
+			_, err := resultsDiscover(ctx, opts)
+			Expect(err).To(MatchError("no previous request results set"))
+		})
+
+		It("Should target the nodes that responded", func() {
+			previous := &RequestOptions{totalStats: NewStats()}
+			previous.totalStats.SetDiscoveredNodes([]string{"web1.example.net", "web2.example.net", "db1.example.net"})
+			previous.totalStats.RecordReceived("web1.example.net")
+			previous.totalStats.RecordReceived("db1.example.net")
+
+			opts.DiscoveryResults = previous
+			Expect(resultsDiscover(ctx, opts)).To(Equal([]string{"web1.example.net", "db1.example.net"}))
+
+			opts.Filter.AddIdentityFilter("/^web/")
+			Expect(resultsDiscover(ctx, opts)).To(Equal([]string{"web1.example.net"}))
+		})
+
+		It("Should only support identity filters", func() {
+			opts.DiscoveryResults = &RequestOptions{totalStats: NewStats()}
+
+			Expect(opts.Filter.AddFactFilter("country", "==", "mt")).To(Succeed())
+			_, err := resultsDiscover(ctx, opts)
+			Expect(err).To(MatchError("the results discovery method only supports identity filters"))
+
+			opts.Filter = protocol.NewFilter()
+			Expect(opts.Filter.AddCompoundFilter(`[{"statement": "role::web"}]`)).To(Succeed())
+			_, err = resultsDiscover(ctx, opts)
+			Expect(err).To(MatchError("the results discovery method only supports identity filters"))
+		})
+	})
+})
@@ -36,6 +36,35 @@ type RequestOptions struct {
 	LimitSize        string
 	DiscoveryStartCB DiscoveryStartFunc
 	DiscoveryEndCB   DiscoveryEndFunc
+	DiscoveryMethod  string
+	DiscoverySource  string
+	DiscoveryResults RequestResult
+
+	// DiscoveryCache is consulted before discovering nodes when set
+	DiscoveryCache        *DiscoveryCache
+	BypassDiscoveryCache  bool
+	RefreshDiscoveryCache bool
+	DiscoveryEndCachedCB  DiscoveryEndCachedFunc
+
+	BatchStartCB BatchStartFunc
+	BatchEndCB   BatchEndFunc
+
+	// AdaptiveBatching grows batches from BatchSize up to AdaptiveBatchMax while batches succeed
+	AdaptiveBatching       bool
+	AdaptiveBatchMax       int
+	AdaptiveBatchThreshold float64
+	BatchThresholdCB       BatchThresholdFunc
+
+	// RetryCount is how many times nodes that did not respond are sent the request again
+	RetryCount   int
+	RetryBackoff time.Duration
+
+	// DDLs checked against the agent versions in rpcutil#agent_inventory replies
+	CompatibilityDDLs   []*agent.DDL
+	IncompatibleAgentCB IncompatibleAgentFunc
+
+	// ReplyValidation checks reply data against the DDL outputs when set
+	ReplyValidation *agent.ReplyValidation
 
 	// merged of all batches
 	totalStats *Stats
@@ -43,6 +72,11 @@ type RequestOptions struct {
 	// per batch
 	stats *Stats
 
+	// every batch performed including retries
+	batchStats []*Stats
+
+	action string
+
 	fw ChoriaFramework
 }
 
@@ -54,6 +88,25 @@ type DiscoveryStartFunc func()
 // error the RPC call will terminate
 type DiscoveryEndFunc func(discovered int, limited int) error
 
+// DiscoveryEndCachedFunc is like DiscoveryEndFunc but also indicates if the nodes came from the discovery cache
+type DiscoveryEndCachedFunc func(discovered int, limited int, cached bool) error
+
+// BatchStartFunc gets called before a batch of nodes is sent the request, batches are numbered from 1
+// and retries of unresponsive nodes are batches too
+type BatchStartFunc func(batch int, nodes []string)
+
+// BatchEndFunc gets called after a batch completed with the stats for just that batch, should this
+// return error the RPC call will terminate
+type BatchEndFunc func(batch int, stats *Stats) error
+
+// BatchThresholdFunc gets called in adaptive batching mode when the rate of failed and unresponsive
+// nodes in a batch exceeds the threshold, should this return error the RPC call will terminate
+type BatchThresholdFunc func(batch int, rate float64, stats *Stats) error
+
+// IncompatibleAgentFunc gets called when a rpcutil#agent_inventory reply shows that a
+// node runs a version of an agent that is incompatible with the DDL used by the client
+type IncompatibleAgentFunc func(sender string, agent string, version string, compat *agent.Compatibility)
+
 // RequestOption is a function capable of setting an option
 type RequestOption func(*RequestOptions)
 
@@ -78,6 +131,7 @@ func NewRequestOptions(fw ChoriaFramework, ddl *agent.DDL) (*RequestOptions, err
 		totalStats:      NewStats(),
 		LimitMethod:     cfg.RPCLimitMethod,
 		LimitSeed:       time.Now().UnixNano(),
+		DiscoveryMethod: BroadcastDiscovery,
 
 		// add discovery timeout to the agent timeout as that's basically an indication of
 		// network overhead, discovery being the smallest possible RPC request it's an indication
@@ -156,6 +210,11 @@ func (o *RequestOptions) Stats() *Stats {
 	return o.totalStats
 }
 
+// BatchStats retrieves the stats for every batch of the completed request in the order they were performed
+func (o *RequestOptions) BatchStats() []*Stats {
+	return o.batchStats
+}
+
 // DiscoveryStartCB sets the function to be called before discovery starts
 func DiscoveryStartCB(h DiscoveryStartFunc) RequestOption {
 	return func(o *RequestOptions) {
@@ -170,6 +229,79 @@ func DiscoveryEndCB(h DiscoveryEndFunc) RequestOption {
 	}
 }
 
+// DiscoveryEndCachedCB sets the function to be called after discovery and node limiting, it is told if the discovery cache was used
+func DiscoveryEndCachedCB(h DiscoveryEndCachedFunc) RequestOption {
+	return func(o *RequestOptions) {
+		o.DiscoveryEndCachedCB = h
+	}
+}
+
+// CacheDiscovery uses c to store and retrieve discovered nodes, see NewDiscoveryCache()
+func CacheDiscovery(c *DiscoveryCache) RequestOption {
+	return func(o *RequestOptions) {
+		o.DiscoveryCache = c
+	}
+}
+
+// BypassDiscoveryCache performs discovery without reading from or updating the discovery cache
+func BypassDiscoveryCache() RequestOption {
+	return func(o *RequestOptions) {
+		o.BypassDiscoveryCache = true
+	}
+}
+
+// RefreshDiscoveryCache performs discovery and stores the result in the discovery cache even when it has a valid entry
+func RefreshDiscoveryCache() RequestOption {
+	return func(o *RequestOptions) {
+		o.RefreshDiscoveryCache = true
+	}
+}
+
+// DiscoveryMethod selects the method used to discover nodes when no Targets() are given, see DiscoveryMethods()
+func DiscoveryMethod(m string) RequestOption {
+	return func(o *RequestOptions) {
+		o.DiscoveryMethod = m
+	}
+}
+
+// DiscoverySource sets the file used by the flatfile and inventory discovery methods
+func DiscoverySource(s string) RequestOption {
+	return func(o *RequestOptions) {
+		o.DiscoverySource = s
+	}
+}
+
+// DiscoveryResults sets the previous request whose responding nodes are targeted by the results discovery method
+func DiscoveryResults(r RequestResult) RequestOption {
+	return func(o *RequestOptions) {
+		o.DiscoveryResults = r
+	}
+}
+
+// WarnIncompatibleAgents checks the agent versions in rpcutil#agent_inventory replies against
+// the given DDLs and logs a warning for every node running an incompatible version of an agent
+func WarnIncompatibleAgents(ddls ...*agent.DDL) RequestOption {
+	return func(o *RequestOptions) {
+		o.CompatibilityDDLs = append(o.CompatibilityDDLs, ddls...)
+	}
+}
+
+// IncompatibleAgentCB sets the function to be called for every node running an incompatible
+// version of an agent checked using WarnIncompatibleAgents
+func IncompatibleAgentCB(h IncompatibleAgentFunc) RequestOption {
+	return func(o *RequestOptions) {
+		o.IncompatibleAgentCB = h
+	}
+}
+
+// ValidateReplies checks the data in every reply against the outputs declared in the DDL, warnings
+// are logged and in strict mode replies that do not match are marked as failed with UnknownError
+func ValidateReplies(v agent.ReplyValidation) RequestOption {
+	return func(o *RequestOptions) {
+		o.ReplyValidation = &v
+	}
+}
+
 // ConnectionName sets the prefix used for various connection names
 //
 // Setting this when making many clients will minimize prometheus
@@ -244,6 +376,51 @@ func InBatches(size int, sleep int) RequestOption {
 	}
 }
 
+// BatchStartCB sets the function to be called before every batch
+func BatchStartCB(h BatchStartFunc) RequestOption {
+	return func(o *RequestOptions) {
+		o.BatchStartCB = h
+	}
+}
+
+// BatchEndCB sets the function to be called after every batch
+func BatchEndCB(h BatchEndFunc) RequestOption {
+	return func(o *RequestOptions) {
+		o.BatchEndCB = h
+	}
+}
+
+// InAdaptiveBatches performs requests in batches that start with initial nodes and double in size up to
+// max after every batch where all nodes responded successfully, a max of 0 does not limit the size. The
+// rollout is aborted when the fraction of failed and unresponsive nodes in a batch exceeds threshold
+// unless a BatchThresholdCB allows it to continue
+func InAdaptiveBatches(initial int, max int, threshold float64, sleep int) RequestOption {
+	return func(o *RequestOptions) {
+		o.BatchSize = initial
+		o.BatchSleep = time.Second * time.Duration(sleep)
+		o.Workers = 1
+		o.AdaptiveBatching = true
+		o.AdaptiveBatchMax = max
+		o.AdaptiveBatchThreshold = threshold
+	}
+}
+
+// BatchThresholdCB sets the function that decides if an adaptive batched rollout continues after a failing batch
+func BatchThresholdCB(h BatchThresholdFunc) RequestOption {
+	return func(o *RequestOptions) {
+		o.BatchThresholdCB = h
+	}
+}
+
+// RetryUnresponsive sends the request up to count more times to nodes that did not respond,
+// waiting backoff before the first retry and doubling the wait for every further retry
+func RetryUnresponsive(count int, backoff time.Duration) RequestOption {
+	return func(o *RequestOptions) {
+		o.RetryCount = count
+		o.RetryBackoff = backoff
+	}
+}
+
 // Replies creates a custom channel for replies and will avoid processing them
 func Replies(r chan *choria.ConnectorMessage) RequestOption {
 	return func(o *RequestOptions) {
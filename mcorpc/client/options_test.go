@@ -170,6 +170,41 @@ var _ = Describe("McoRPC/Client/Options", func() {
 		})
 	})
 
+	Describe("BatchStartCB / BatchEndCB", func() {
+		It("Should set the callbacks", func() {
+			started := false
+			ended := false
+
+			BatchStartCB(func(_ int, _ []string) { started = true })(o)
+			BatchEndCB(func(_ int, _ *Stats) error { ended = true; return nil })(o)
+
+			o.BatchStartCB(1, []string{})
+			Expect(o.BatchEndCB(1, NewStats())).To(Succeed())
+			Expect(started).To(BeTrue())
+			Expect(ended).To(BeTrue())
+		})
+	})
+
+	Describe("InAdaptiveBatches", func() {
+		It("Should set the sizes, threshold and sleep", func() {
+			InAdaptiveBatches(1, 10, 0.1, 5)(o)
+			Expect(o.AdaptiveBatching).To(BeTrue())
+			Expect(o.BatchSize).To(Equal(1))
+			Expect(o.AdaptiveBatchMax).To(Equal(10))
+			Expect(o.AdaptiveBatchThreshold).To(Equal(0.1))
+			Expect(o.BatchSleep).To(Equal(5 * time.Second))
+			Expect(o.Workers).To(Equal(1))
+		})
+	})
+
+	Describe("RetryUnresponsive", func() {
+		It("Should set the retry count and backoff", func() {
+			RetryUnresponsive(3, time.Second)(o)
+			Expect(o.RetryCount).To(Equal(3))
+			Expect(o.RetryBackoff).To(Equal(time.Second))
+		})
+	})
+
 	Describe("Replies", func() {
 		It("Should set the channel and disable the handlers", func() {
 			Replies(make(chan *choria.ConnectorMessage, 123))(o)
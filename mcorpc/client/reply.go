@@ -0,0 +1,182 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	addl "github.com/choria-io/mcorpc-agent-provider/mcorpc/ddl/agent"
+)
+
+// ParseData decodes the reply data into a map where every output is converted to the type declared
+// in the DDL for the action, integers become int64 and floats float64. Outputs missing from the reply
+// are set to their defaults
+func (r *RPCReply) ParseData(act *addl.Action) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+
+	if len(r.Data) > 0 && string(r.Data) != "null" {
+		dec := json.NewDecoder(bytes.NewReader(r.Data))
+		dec.UseNumber()
+
+		err := dec.Decode(&data)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode reply data: %s", err)
+		}
+	}
+
+	act.SetOutputDefaults(data)
+
+	for name, val := range data {
+		t := ""
+		if output, ok := act.Output[name]; ok {
+			t = output.Type
+		}
+
+		converted, err := convertOutput(t, val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for output '%s': %s", name, err)
+		}
+
+		data[name] = converted
+	}
+
+	return data, nil
+}
+
+// ParseDataInto decodes the reply data converted using ParseData into target, which should be
+// a pointer to a struct with JSON tags matching the outputs
+func (r *RPCReply) ParseDataInto(act *addl.Action, target interface{}) error {
+	data, err := r.ParseData(act)
+	if err != nil {
+		return err
+	}
+
+	j, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("could not encode reply data: %s", err)
+	}
+
+	err = json.Unmarshal(j, target)
+	if err != nil {
+		return fmt.Errorf("could not decode reply data: %s", err)
+	}
+
+	return nil
+}
+
+// convertOutput converts val to the DDL type t, values of outputs without a known type
+// only have their numbers converted to float64
+func convertOutput(t string, val interface{}) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+
+	switch strings.ToLower(t) {
+	case "integer":
+		switch v := val.(type) {
+		case json.Number:
+			i, err := v.Int64()
+			if err == nil {
+				return i, nil
+			}
+
+			f, err := v.Float64()
+			if err != nil || f != math.Trunc(f) {
+				return nil, fmt.Errorf("'%s' is not a valid integer", v)
+			}
+
+			return int64(f), nil
+
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("'%v' is not a valid integer", v)
+			}
+
+			return int64(v), nil
+
+		case int64:
+			return v, nil
+
+		case string:
+			i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("'%s' is not a valid integer", v)
+			}
+
+			return i, nil
+		}
+
+	case "float", "number":
+		switch v := val.(type) {
+		case json.Number:
+			return v.Float64()
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case string:
+			return addl.ValToDDLType(t, strings.TrimSpace(v))
+		}
+
+	case "boolean":
+		switch v := val.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return addl.ValToDDLType(t, strings.TrimSpace(v))
+		}
+
+	case "string", "list":
+		switch v := val.(type) {
+		case string:
+			return v, nil
+		case json.Number, bool, float64, int64:
+			return fmt.Sprintf("%v", v), nil
+		}
+
+	case "hash":
+		switch v := val.(type) {
+		case map[string]interface{}:
+			return plainNumbers(v), nil
+		case string:
+			return addl.ValToDDLType(t, v)
+		}
+
+	case "array":
+		switch v := val.(type) {
+		case []interface{}:
+			return plainNumbers(v), nil
+		case string:
+			return addl.ValToDDLType(t, v)
+		}
+
+	default:
+		return plainNumbers(val), nil
+	}
+
+	return nil, fmt.Errorf("cannot convert %T to %s", val, strings.ToLower(t))
+}
+
+// plainNumbers replaces json.Number values in val with float64 like encoding/json does by default
+func plainNumbers(val interface{}) interface{} {
+	switch v := val.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+
+	case map[string]interface{}:
+		for k, i := range v {
+			v[k] = plainNumbers(i)
+		}
+
+	case []interface{}:
+		for k, i := range v {
+			v[k] = plainNumbers(i)
+		}
+	}
+
+	return val
+}
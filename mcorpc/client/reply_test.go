@@ -0,0 +1,82 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/choria-io/mcorpc-agent-provider/mcorpc/ddl/agent"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("McoRPC/Client/RPCReply", func() {
+	var act *agent.Action
+
+	BeforeEach(func() {
+		act = &agent.Action{
+			Name: "test",
+			Output: map[string]*agent.ActionOutputItem{
+				"count":   {Type: "integer"},
+				"load":    {Type: "float"},
+				"enabled": {Type: "boolean"},
+				"name":    {Type: "string"},
+				"labels":  {Type: "hash"},
+				"members": {Type: "array"},
+				"status":  {Type: "string", Default: "unknown"},
+				"other":   {},
+			},
+		}
+	})
+
+	Describe("ParseData", func() {
+		It("Should convert outputs to their declared types", func() {
+			reply := &RPCReply{Data: json.RawMessage(`{"count": 9007199254740993, "load": 1, "enabled": "true", "name": 1, "labels": {"a": 1}, "members": [1.5], "other": 2, "extra": "x"}`)}
+
+			data, err := reply.ParseData(act)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(map[string]interface{}{
+				"count":   int64(9007199254740993),
+				"load":    float64(1),
+				"enabled": true,
+				"name":    "1",
+				"labels":  map[string]interface{}{"a": float64(1)},
+				"members": []interface{}{1.5},
+				"status":  "unknown",
+				"other":   float64(2),
+				"extra":   "x",
+			}))
+		})
+
+		It("Should detect invalid values", func() {
+			reply := &RPCReply{Data: json.RawMessage(`{"count": 1.5}`)}
+			_, err := reply.ParseData(act)
+			Expect(err).To(MatchError("invalid value for output 'count': '1.5' is not a valid integer"))
+
+			reply = &RPCReply{Data: json.RawMessage(`{"labels": [1]}`)}
+			_, err = reply.ParseData(act)
+			Expect(err).To(MatchError("invalid value for output 'labels': cannot convert []interface {} to hash"))
+		})
+
+		It("Should handle empty replies", func() {
+			data, err := (&RPCReply{}).ParseData(act)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(map[string]interface{}{"status": "unknown"}))
+		})
+	})
+
+	Describe("ParseDataInto", func() {
+		It("Should decode into structs", func() {
+			result := struct {
+				Count  int64  `json:"count"`
+				Status string `json:"status"`
+				Name   string `json:"name"`
+			}{}
+
+			reply := &RPCReply{Data: json.RawMessage(`{"count": "10", "name": "x"}`)}
+			Expect(reply.ParseDataInto(act, &result)).ToNot(HaveOccurred())
+			Expect(result.Count).To(Equal(int64(10)))
+			Expect(result.Status).To(Equal("unknown"))
+			Expect(result.Name).To(Equal("x"))
+		})
+	})
+})
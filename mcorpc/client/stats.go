@@ -17,6 +17,9 @@ type Stats struct {
 	outstandingNodes   *NodeList
 	unexpectedRespones *NodeList
 
+	// attempts is how many times each node was sent the request
+	attempts map[string]int
+
 	responses atomic.Int32
 	passed    atomic.Int32
 	failed    atomic.Int32
@@ -29,8 +32,9 @@ type Stats struct {
 	publishTotal time.Duration
 	publishing   bool
 
-	discoveryStart time.Time
-	discoveryEnd   time.Time
+	discoveryStart  time.Time
+	discoveryEnd    time.Time
+	discoveryCached bool
 
 	agent  string
 	action string
@@ -44,6 +48,7 @@ func NewStats() *Stats {
 		discoveredNodes:    []string{},
 		outstandingNodes:   NewNodeList(),
 		unexpectedRespones: NewNodeList(),
+		attempts:           make(map[string]int),
 		mu:                 &sync.Mutex{},
 	}
 }
@@ -64,6 +69,13 @@ func (s *Stats) Merge(other *Stats) error {
 
 	s.unexpectedRespones.AddHosts(other.UnexpectedResponseFrom()...)
 
+	attempts := other.NodeAttempts()
+	s.mu.Lock()
+	for node, count := range attempts {
+		s.attempts[node] += count
+	}
+	s.mu.Unlock()
+
 	s.passed.Add(other.passed.Load())
 	s.failed.Add(other.failed.Load())
 
@@ -133,6 +145,53 @@ func (s *Stats) SetDiscoveredNodes(nodes []string) {
 	s.outstandingNodes.AddHosts(nodes...)
 }
 
+// RecordAttempt records that the request was sent to nodes
+func (s *Stats) RecordAttempt(nodes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, node := range nodes {
+		s.attempts[node]++
+	}
+}
+
+// Attempts is how many times the request was sent to a node
+func (s *Stats) Attempts(node string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.attempts[node]
+}
+
+// NodeAttempts is how many times the request was sent to every node
+func (s *Stats) NodeAttempts() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts := make(map[string]int)
+	for node, count := range s.attempts {
+		attempts[node] = count
+	}
+
+	return attempts
+}
+
+// UncontactedNodes are the discovered nodes that were never sent the request, for example because
+// the request was cancelled or aborted before their batch
+func (s *Stats) UncontactedNodes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nodes := []string{}
+	for _, node := range s.discoveredNodes {
+		if s.attempts[node] == 0 {
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes
+}
+
 // FailedRequestInc increments the failed request counter by one
 func (s *Stats) FailedRequestInc() {
 	s.failed.Inc()
@@ -273,6 +332,22 @@ func (s *Stats) EndDiscover() {
 	}
 }
 
+// SetDiscoveryCached records if the discovered nodes came from the discovery cache
+func (s *Stats) SetDiscoveryCached(cached bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.discoveryCached = cached
+}
+
+// DiscoveryCached determines if the discovered nodes came from the discovery cache
+func (s *Stats) DiscoveryCached() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.discoveryCached
+}
+
 // DiscoveryDuration determines how long discovery took, 0 and error when discovery was not done
 func (s *Stats) DiscoveryDuration() (time.Duration, error) {
 	s.mu.Lock()
@@ -45,6 +45,30 @@ var _ = Describe("McoRPC/Client/Stats", func() {
 				Expect(s.OKCount()).To(Equal(1))
 				Expect(s.PublishDuration()).To(BeNumerically("~", 10*time.Second, 10*time.Millisecond))
 			})
+
+			It("Should merge attempts", func() {
+				other := NewStats()
+
+				s.RecordAttempt("host1", "host2")
+				other.RecordAttempt("host2")
+
+				s.Merge(other)
+
+				Expect(s.Attempts("host1")).To(Equal(1))
+				Expect(s.Attempts("host2")).To(Equal(2))
+				Expect(s.Attempts("host3")).To(Equal(0))
+				Expect(s.NodeAttempts()).To(Equal(map[string]int{"host1": 1, "host2": 2}))
+			})
+		})
+
+		Describe("UncontactedNodes", func() {
+			It("Should list nodes without attempts", func() {
+				s.SetDiscoveredNodes([]string{"host1", "host2", "host3"})
+				Expect(s.UncontactedNodes()).To(Equal([]string{"host1", "host2", "host3"}))
+
+				s.RecordAttempt("host2")
+				Expect(s.UncontactedNodes()).To(Equal([]string{"host1", "host3"}))
+			})
 		})
 
 		Describe("SetAgent / Agent", func() {
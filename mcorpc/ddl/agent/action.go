@@ -3,6 +3,7 @@ package agent
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"regexp"
 	"sort"
@@ -10,11 +11,7 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/choria-io/go-validator/ipaddress"
-	"github.com/choria-io/go-validator/ipv4"
-	"github.com/choria-io/go-validator/ipv6"
 	"github.com/choria-io/go-validator/regex"
-	"github.com/choria-io/go-validator/shellsafe"
 )
 
 // Action describes an individual action in an agent
@@ -49,10 +46,29 @@ type ActionInputItem struct {
 	Validation  string      `json:"validation,omitempty"`
 	MaxLength   int         `json:"maxlength,omitempty"`
 	Enum        []string    `json:"list,omitempty"`
+
+	// Minimum and Maximum restrict the range of integer, float and number inputs
+	Minimum *float64 `json:"minimum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty"`
+
+	// MinLength is the minimum length of strings or the minimum items in arrays, for
+	// arrays MaxLength is the maximum amount of items
+	MinLength int `json:"minlength,omitempty"`
+
+	// Items describes every item in an array input
+	Items *ActionInputItem `json:"items,omitempty"`
+
+	// Properties describes the fields of a hash input, fields not listed are allowed
+	Properties map[string]*ActionInputItem `json:"properties,omitempty"`
 }
 
 // AggregateResultJSON receives a JSON reply and aggregate all the data found in it
 func (a *Action) AggregateResultJSON(jres []byte) error {
+	return a.AggregateSenderResultJSON("", jres)
+}
+
+// AggregateSenderResultJSON receives a JSON reply from sender and aggregate all the data found in it
+func (a *Action) AggregateSenderResultJSON(sender string, jres []byte) error {
 	res := make(map[string]interface{})
 
 	err := json.Unmarshal(jres, &res)
@@ -60,7 +76,7 @@ func (a *Action) AggregateResultJSON(jres []byte) error {
 		return fmt.Errorf("could not parse result as JSON data: %s", err)
 	}
 
-	return a.AggregateResult(res)
+	return a.AggregateSenderResult(sender, res)
 }
 
 // AggregateResult receives a result and aggregate all the data found in it, most
@@ -68,6 +84,12 @@ func (a *Action) AggregateResultJSON(jres []byte) error {
 // and we do not want to fail a reply just because aggregation failed, thus this
 // is basically a best efforts kind of thing on purpose
 func (a *Action) AggregateResult(result map[string]interface{}) error {
+	return a.AggregateSenderResult("", result)
+}
+
+// AggregateSenderResult receives a result from sender and aggregate all the data found in it,
+// aggregators like chart can use the sender to identify where values came from
+func (a *Action) AggregateSenderResult(sender string, result map[string]interface{}) error {
 	a.Lock()
 	defer a.Unlock()
 
@@ -75,9 +97,7 @@ func (a *Action) AggregateResult(result map[string]interface{}) error {
 		a.agg = newActionAggregators(a)
 	}
 
-	for k, v := range result {
-		a.agg.aggregateItem(k, v)
-	}
+	a.agg.aggregateResult(sender, result)
 
 	return nil
 }
@@ -121,6 +141,42 @@ func (a *Action) AggregateSummaryFormattedStrings() (map[string][]string, error)
 	return a.agg.resultStringsFormatted(), nil
 }
 
+// AggregateState produce a JSON representation of the internal state of every aggregate
+// that can later be combined with other results using MergeAggregateState()
+func (a *Action) AggregateState() ([]byte, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.agg == nil {
+		a.agg = newActionAggregators(a)
+	}
+
+	return a.agg.state()
+}
+
+// MergeAggregateState merges aggregate state produced by AggregateState(), typically from
+// another batch or client, into the aggregates of this action
+func (a *Action) MergeAggregateState(state []byte) error {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.agg == nil {
+		a.agg = newActionAggregators(a)
+	}
+
+	return a.agg.mergeState(state)
+}
+
+// MergeAggregates merges the aggregate results of other into this action
+func (a *Action) MergeAggregates(other *Action) error {
+	state, err := other.AggregateState()
+	if err != nil {
+		return err
+	}
+
+	return a.MergeAggregateState(state)
+}
+
 // InputNames retrieves all valid input names
 func (a *Action) InputNames() (names []string) {
 	names = []string{}
@@ -306,32 +362,115 @@ func (a *Action) ValidateInputValue(input string, val interface{}) (warnings []s
 		return warnings, fmt.Errorf("unknown input '%s'", input)
 	}
 
+	return i.validateValue(val)
+}
+
+// Constraints describes the validation performed on the input in plain words
+func (i *ActionInputItem) Constraints() []string {
+	rules := []string{}
+
+	switch strings.ToLower(i.Type) {
+	case "list":
+		if len(i.Enum) > 0 {
+			rules = append(rules, "one of "+strings.Join(i.Enum, ", "))
+		}
+
+	case "string":
+		if i.Validation != "" {
+			rules = append(rules, i.Validation)
+		}
+
+		if i.MinLength > 0 {
+			rules = append(rules, fmt.Sprintf("min length %d", i.MinLength))
+		}
+
+		if i.MaxLength > 0 {
+			rules = append(rules, fmt.Sprintf("max length %d", i.MaxLength))
+		}
+
+	case "integer", "float", "number":
+		if i.Minimum != nil {
+			rules = append(rules, "minimum "+strconv.FormatFloat(*i.Minimum, 'f', -1, 64))
+		}
+
+		if i.Maximum != nil {
+			rules = append(rules, "maximum "+strconv.FormatFloat(*i.Maximum, 'f', -1, 64))
+		}
+
+	case "array":
+		if i.MinLength > 0 {
+			rules = append(rules, fmt.Sprintf("at least %d items", i.MinLength))
+		}
+
+		if i.MaxLength > 0 {
+			rules = append(rules, fmt.Sprintf("at most %d items", i.MaxLength))
+		}
+
+		if i.Items != nil {
+			item := "items of type " + i.Items.Type
+			if c := i.Items.Constraints(); len(c) > 0 {
+				item = fmt.Sprintf("%s (%s)", item, strings.Join(c, ", "))
+			}
+
+			rules = append(rules, item)
+		}
+
+	case "hash":
+		if len(i.Properties) > 0 {
+			fields := []string{}
+			for name := range i.Properties {
+				fields = append(fields, name)
+			}
+			sort.Strings(fields)
+
+			rules = append(rules, "fields "+strings.Join(fields, ", "))
+		}
+	}
+
+	return rules
+}
+
+// validateValue validates val against the type and constraints of the input, items of
+// arrays and fields of hashes are validated recursively
+func (i *ActionInputItem) validateValue(val interface{}) (warnings []string, err error) {
+	warnings = []string{}
+
 	switch strings.ToLower(i.Type) {
 	case "integer":
 		if !isAnyInt(val) {
 			return warnings, fmt.Errorf("is not an integer")
 		}
 
+		return warnings, i.validateRange(val)
+
 	case "number":
 		if !isNumber(val) {
 			return warnings, fmt.Errorf("is not a number")
 		}
 
+		return warnings, i.validateRange(val)
+
 	case "float":
 		if !isFloat64(val) {
 			return warnings, fmt.Errorf("is not a float")
 		}
 
+		return warnings, i.validateRange(val)
+
 	case "string":
 		if !isString(val) {
 			return warnings, fmt.Errorf("is not a string")
 		}
 
+		sval := reflect.ValueOf(val).String()
+		if i.MinLength > 0 && len(sval) < i.MinLength {
+			return warnings, fmt.Errorf("is shorter than %d characters", i.MinLength)
+		}
+
 		if i.MaxLength == 0 {
 			return warnings, nil
 		}
 
-		sval := val.(string)
 		if len(sval) > i.MaxLength {
 			return warnings, fmt.Errorf("is longer than %d characters", i.MaxLength)
 		}
@@ -374,11 +513,15 @@ func (a *Action) ValidateInputValue(input string, val interface{}) (warnings []s
 			return warnings, fmt.Errorf("is not a hash map")
 		}
 
+		return i.validateProperties(reflect.ValueOf(val))
+
 	case "array":
 		if !isArray(val) {
 			return warnings, fmt.Errorf("is not an array")
 		}
 
+		return i.validateItems(reflect.ValueOf(val))
+
 	default:
 		return warnings, fmt.Errorf("unsupported input type '%s'", i.Type)
 	}
@@ -386,29 +529,105 @@ func (a *Action) ValidateInputValue(input string, val interface{}) (warnings []s
 	return warnings, nil
 }
 
-func validateStringValidation(validation string, value string) (warnings []string, err error) {
+func (i *ActionInputItem) validateRange(val interface{}) error {
+	f := numericValue(val)
+
+	if i.Minimum != nil && f < *i.Minimum {
+		return fmt.Errorf("should be at least %s", strconv.FormatFloat(*i.Minimum, 'f', -1, 64))
+	}
+
+	if i.Maximum != nil && f > *i.Maximum {
+		return fmt.Errorf("should be at most %s", strconv.FormatFloat(*i.Maximum, 'f', -1, 64))
+	}
+
+	return nil
+}
+
+// validateNestedValue validates items of arrays and fields of hashes, numbers in those are
+// decoded from JSON as float64 so whole floats are accepted as integers
+func (i *ActionInputItem) validateNestedValue(val interface{}) (warnings []string, err error) {
+	if strings.ToLower(i.Type) == "integer" && isWholeFloat(val) {
+		val = int64(reflect.ValueOf(val).Float())
+	}
+
+	return i.validateValue(val)
+}
+
+func (i *ActionInputItem) validateItems(val reflect.Value) (warnings []string, err error) {
 	warnings = []string{}
 
-	switch validation {
-	case "shellsafe":
-		_, err = shellsafe.Validate(value)
-		return warnings, err
+	if i.MinLength > 0 && val.Len() < i.MinLength {
+		return warnings, fmt.Errorf("should have at least %d items", i.MinLength)
+	}
 
-	case "ipv4address":
-		_, err := ipv4.ValidateString(value)
-		return warnings, err
+	if i.MaxLength > 0 && val.Len() > i.MaxLength {
+		return warnings, fmt.Errorf("should have at most %d items", i.MaxLength)
+	}
 
-	case "ipv6address":
-		_, err := ipv6.ValidateString(value)
-		return warnings, err
+	if i.Items == nil {
+		return warnings, nil
+	}
 
-	case "ipaddress":
-		_, err := ipaddress.ValidateString(value)
-		return warnings, err
+	for idx := 0; idx < val.Len(); idx++ {
+		w, err := i.Items.validateNestedValue(val.Index(idx).Interface())
+		warnings = append(warnings, w...)
+		if err != nil {
+			return warnings, fmt.Errorf("item %d %s", idx, err)
+		}
 	}
 
-	namedValidator, err := regexp.MatchString("^[a-z]", validation)
-	if namedValidator || err != nil {
+	return warnings, nil
+}
+
+func (i *ActionInputItem) validateProperties(val reflect.Value) (warnings []string, err error) {
+	warnings = []string{}
+
+	if len(i.Properties) == 0 {
+		return warnings, nil
+	}
+
+	if val.Type().Key().Kind() != reflect.String {
+		return warnings, fmt.Errorf("should have string keys")
+	}
+
+	names := []string{}
+	for name := range i.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := i.Properties[name]
+
+		fval := val.MapIndex(reflect.ValueOf(name).Convert(val.Type().Key()))
+		if !fval.IsValid() {
+			if !field.Optional {
+				return warnings, fmt.Errorf("field '%s' is required", name)
+			}
+
+			continue
+		}
+
+		w, err := field.validateNestedValue(fval.Interface())
+		warnings = append(warnings, w...)
+		if err != nil {
+			return warnings, fmt.Errorf("field '%s' %s", name, err)
+		}
+	}
+
+	return warnings, nil
+}
+
+func validateStringValidation(validation string, value string) (warnings []string, err error) {
+	warnings = []string{}
+
+	validator, ok := namedValidator(validation)
+	if ok {
+		return warnings, validator(value)
+	}
+
+	named, err := regexp.MatchString("^[a-z]", validation)
+	if named || err != nil {
 		return []string{fmt.Sprintf("Unsupported validator '%s'", validation)}, nil
 	}
 
@@ -478,6 +697,31 @@ func isArray(i interface{}) bool {
 	return kind == reflect.Array || kind == reflect.Slice
 }
 
+// isWholeFloat checks for floats without a fractional part like integers decoded from JSON
+func isWholeFloat(i interface{}) bool {
+	if !isAnyFloat(i) {
+		return false
+	}
+
+	f := reflect.ValueOf(i).Float()
+
+	return f == math.Trunc(f) && !math.IsInf(f, 0)
+}
+
+// numericValue converts any int or float to a float64
+func numericValue(i interface{}) float64 {
+	v := reflect.ValueOf(i)
+
+	switch {
+	case isAnyInt(i):
+		return float64(v.Int())
+	case isAnyFloat(i):
+		return v.Float()
+	default:
+		return 0
+	}
+}
+
 func isBool(i interface{}) bool {
 	return reflect.ValueOf(i).Kind() == reflect.Bool
 }
@@ -242,6 +242,103 @@ var _ = Describe("McoRPC/DDL/Agent/Action", func() {
 			Expect(warnings).To(HaveLen(0))
 			Expect(err).ToNot(HaveOccurred())
 		})
+
+		It("Should validate numeric ranges", func() {
+			min := 1.0
+			max := 10.0
+			act.Input["int"].Minimum = &min
+			act.Input["int"].Maximum = &max
+			act.Input["float"].Maximum = &max
+
+			_, err := act.ValidateInputValue("int", 5)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = act.ValidateInputValue("int", 5.5)
+			Expect(err).To(MatchError("is not an integer"))
+
+			_, err = act.ValidateInputValue("int", 0)
+			Expect(err).To(MatchError("should be at least 1"))
+
+			_, err = act.ValidateInputValue("int", int64(11))
+			Expect(err).To(MatchError("should be at most 10"))
+
+			_, err = act.ValidateInputValue("float", 10.5)
+			Expect(err).To(MatchError("should be at most 10"))
+		})
+
+		It("Should validate array length and items", func() {
+			act.Input["array"].MinLength = 1
+			act.Input["array"].MaxLength = 3
+			act.Input["array"].Items = &ActionInputItem{Type: "list", Enum: []string{"web", "db"}}
+
+			_, err := act.ValidateInputValue("array", []interface{}{"web", "db"})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = act.ValidateInputValue("array", []interface{}{})
+			Expect(err).To(MatchError("should have at least 1 items"))
+
+			_, err = act.ValidateInputValue("array", []string{"web", "web", "web", "web"})
+			Expect(err).To(MatchError("should have at most 3 items"))
+
+			_, err = act.ValidateInputValue("array", []interface{}{"web", "mail"})
+			Expect(err).To(MatchError("item 1 should be one of web, db"))
+		})
+
+		It("Should accept integers decoded from JSON in arrays and hashes", func() {
+			nested := Action{
+				Input: map[string]*ActionInputItem{
+					"ports": &ActionInputItem{Type: "array", Optional: true, Items: &ActionInputItem{Type: "integer"}},
+					"cfg":   &ActionInputItem{Type: "hash", Optional: true, Properties: map[string]*ActionInputItem{"port": {Type: "integer"}}},
+				},
+			}
+
+			converted, _, err := nested.ValidateAndConvertToDDLTypes(map[string]string{"ports": "[80,443]", "cfg": `{"port":443}`})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(converted["ports"]).To(Equal([]interface{}{80.0, 443.0}))
+
+			_, err = nested.ValidateRequestJSON([]byte(`{"ports":[80,443],"cfg":{"port":443}}`))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = nested.ValidateRequestJSON([]byte(`{"ports":[80.5]}`))
+			Expect(err).To(MatchError("validation failed for input 'ports': item 0 is not an integer"))
+
+			_, err = nested.ValidateRequestJSON([]byte(`{"cfg":{"port":"443"}}`))
+			Expect(err).To(MatchError("validation failed for input 'cfg': field 'port' is not an integer"))
+		})
+
+		It("Should validate hash fields", func() {
+			max := 65535.0
+			act.Input["hash"].Properties = map[string]*ActionInputItem{
+				"host": {Type: "string", MaxLength: 20, Validation: "^[a-z.]+$"},
+				"port": {Type: "integer", Maximum: &max},
+				"tags": {Type: "array", Optional: true, Items: &ActionInputItem{Type: "string"}},
+			}
+
+			_, err := act.ValidateInputValue("hash", map[string]interface{}{"host": "example.net", "port": 443, "extra": true})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = act.ValidateInputValue("hash", map[string]interface{}{"host": "example.net"})
+			Expect(err).To(MatchError("field 'port' is required"))
+
+			_, err = act.ValidateInputValue("hash", map[string]interface{}{"host": "example.net", "port": 70000})
+			Expect(err).To(MatchError("field 'port' should be at most 65535"))
+
+			_, err = act.ValidateInputValue("hash", map[string]interface{}{"host": "EXAMPLE", "port": 80})
+			Expect(err).To(MatchError("field 'host' input does not match '^[a-z.]+$'"))
+
+			_, err = act.ValidateInputValue("hash", map[string]interface{}{"host": "example.net", "port": 80, "tags": []interface{}{"a", 1}})
+			Expect(err).To(MatchError("field 'tags' item 1 is not a string"))
+
+			_, err = act.ValidateInputValue("hash", map[int]string{1: "x"})
+			Expect(err).To(MatchError("should have string keys"))
+		})
+
+		It("Should validate string length", func() {
+			act.Input["string"].MinLength = 3
+
+			_, err := act.ValidateInputValue("string", "ab")
+			Expect(err).To(MatchError("is shorter than 3 characters"))
+		})
 	})
 
 	Describe("SetOutputDefaults", func() {
@@ -9,11 +9,13 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/choria-io/go-choria/server/agents"
+	config "github.com/choria-io/go-config"
 )
 
 // DDL represents the schema of a mcorpc agent and is at a basic level
@@ -25,8 +27,44 @@ type DDL struct {
 	SourceLocation string           `json:"-"`
 }
 
-// New creates a new DDL from a JSON file
-func New(file string) (*DDL, error) {
+// LoadOption configures how DDL files are loaded
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	strictValidators bool
+}
+
+// StrictValidators fails loading DDLs that use unknown named validators rather than
+// accepting any input for them, DDLs using SchemaV2URL are always loaded strictly
+func StrictValidators(strict bool) LoadOption {
+	return func(o *loadOptions) {
+		o.strictValidators = strict
+	}
+}
+
+// StrictValidatorsFromConfig enables StrictValidators when the StrictValidatorsOption is set in the configuration
+func StrictValidatorsFromConfig(cfg *config.Config) LoadOption {
+	return StrictValidators(configBool(cfg, StrictValidatorsOption))
+}
+
+var configTrueRe = regexp.MustCompile(`(?i)^(1|yes|true|y|t)$`)
+
+// configBool checks if a plugin option is set to a true like value
+func configBool(cfg *config.Config, option string) bool {
+	if cfg == nil || !cfg.HasOption(option) {
+		return false
+	}
+
+	return configTrueRe.MatchString(strings.TrimSpace(cfg.Option(option, "false")))
+}
+
+// New creates a new DDL from a JSON file, the DDL is validated against the embedded schema,
+// files with the .ddl extension are parsed as legacy Ruby DDLs using NewFromRuby
+func New(file string, opts ...LoadOption) (*DDL, error) {
+	if filepath.Ext(file) == ".ddl" {
+		return NewFromRuby(file, opts...)
+	}
+
 	ddl := &DDL{
 		SourceLocation: file,
 	}
@@ -41,33 +79,30 @@ func New(file string) (*DDL, error) {
 		return nil, fmt.Errorf("could not parse JSON data in %s: %s", file, err)
 	}
 
-	ddl.normalize()
-
-	return ddl, nil
-}
-
-// Find looks in the supplied libdirs for a DDL file for a specific agent
-func Find(agent string, libdirs []string) (ddl *DDL, err error) {
-	EachFile(libdirs, func(n string, f string) bool {
-		if n == agent {
-			ddl, err = New(f)
-			return true
-		}
-
-		return false
-	})
+	if !validSchemaURL(ddl.Schema) {
+		return nil, fmt.Errorf("unsupported DDL schema '%s' in %s", ddl.Schema, file)
+	}
 
+	err = ValidateJSON(dat)
 	if err != nil {
-		return nil, fmt.Errorf("could not load agent %s: %s", agent, err)
+		return nil, fmt.Errorf("invalid DDL %s: %s", file, err)
 	}
 
-	if ddl == nil {
-		return nil, fmt.Errorf("could not find DDL file for %s", agent)
+	err = ddl.checkValidators(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DDL %s: %s", file, err)
 	}
 
+	ddl.normalize()
+
 	return ddl, nil
 }
 
+// Find looks in the supplied libdirs for a DDL file for a specific agent using the SharedRegistry for the libdirs
+func Find(agent string, libdirs []string, opts ...LoadOption) (ddl *DDL, err error) {
+	return SharedRegistry(libdirs, opts...).Find(agent)
+}
+
 // EachFile calls cb with a path to every found agent DDL, stops looking when br is true
 func EachFile(libdirs []string, cb func(name string, path string) (br bool)) {
 	for _, dir := range libdirs {
@@ -82,13 +117,13 @@ func EachFile(libdirs []string, cb func(name string, path string) (br bool)) {
 				return nil
 			}
 
-			_, name := filepath.Split(path)
-			extension := filepath.Ext(name)
-
-			if extension != ".json" {
+			if !LoadableFile(path) {
 				return nil
 			}
 
+			_, name := filepath.Split(path)
+			extension := filepath.Ext(name)
+
 			cb(strings.TrimSuffix(name, extension), path)
 
 			return nil
@@ -96,6 +131,22 @@ func EachFile(libdirs []string, cb func(name string, path string) (br bool)) {
 	}
 }
 
+// LoadableFile determines if path is a DDL file that should be loaded, Ruby DDL files
+// are only loaded when there is no JSON DDL for the same agent next to them
+func LoadableFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".json":
+		return true
+
+	case ".ddl":
+		_, err := os.Stat(strings.TrimSuffix(path, ".ddl") + ".json")
+		return os.IsNotExist(err)
+
+	default:
+		return false
+	}
+}
+
 func (d *DDL) normalize() {
 	for _, action := range d.Actions {
 		if action.Display == "" {
@@ -163,12 +214,30 @@ func (d *DDL) ToRuby() (string, error) {
 				return `"."`
 			}
 
-			switch v {
-			case "shellsafe", "ipv4address", "ipv6address", "ipaddress":
+			if _, ok := namedValidator(v); ok {
 				return ":" + v
-			default:
-				return `'` + v + `'`
 			}
+
+			return `'` + v + `'`
+		},
+
+		"aggregateArgs": func(a ActionAggregateItem) string {
+			return a.rubyArguments()
+		},
+
+		"rubyValue": func(v interface{}) (string, error) {
+			j, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+
+			var val interface{}
+			err = json.Unmarshal(j, &val)
+			if err != nil {
+				return "", err
+			}
+
+			return rubyLiteral(val), nil
 		},
 
 		"enum2list": func(v []string) string {
@@ -292,6 +361,24 @@ action "{{ $action.Name }}", :description => "{{ $action.Description }}" do
 {{- end }}
 {{- if eq $input.Type "list" }}
         :list        => {{ $input.Enum | enum2list }},
+{{- end }}
+{{- if $input.MinLength }}
+        :minlength   => {{ $input.MinLength }},
+{{- end }}
+{{- if and $input.MaxLength (ne $input.Type "string") }}
+        :maxlength   => {{ $input.MaxLength }},
+{{- end }}
+{{- if $input.Minimum }}
+        :minimum     => {{ $input.Minimum | rubyValue }},
+{{- end }}
+{{- if $input.Maximum }}
+        :maximum     => {{ $input.Maximum | rubyValue }},
+{{- end }}
+{{- if $input.Items }}
+        :items       => {{ $input.Items | rubyValue }},
+{{- end }}
+{{- if $input.Properties }}
+        :properties  => {{ $input.Properties | rubyValue }},
 {{- end }}
         :optional    => {{ $input.Optional }}
 
@@ -312,7 +399,7 @@ action "{{ $action.Name }}", :description => "{{ $action.Description }}" do
 {{- if $action.Aggregation }}
   summarize do
 {{- range $aname, $aggregate := $action.Aggregation }}
-    aggregate {{ $aggregate.Function }}(:{{ $aggregate.OutputName }})
+    aggregate {{ $aggregate.Function }}({{ $aggregate | aggregateArgs }})
 {{- end }}
   end
 {{- end }}
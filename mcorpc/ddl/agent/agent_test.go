@@ -115,6 +115,33 @@ var _ = Describe("McoRPC/DDL/Agent", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(out).ToNot(HaveLen(0))
 		})
+
+		It("Should include aggregate options", func() {
+			act, err := pkg.ActionInterface("update")
+			Expect(err).ToNot(HaveOccurred())
+
+			act.Aggregation = []ActionAggregateItem{
+				{Function: "sum", Arguments: json.RawMessage(`["updated", {"format": "Updated #{count}: %d"}]`)},
+				{Function: "max", Arguments: json.RawMessage(`["uptime"]`)},
+				{Function: "stats", Arguments: json.RawMessage(`["duration", {"percentiles": [50, 99.9]}]`)},
+				{Function: "summary", Arguments: json.RawMessage(`["packages.*.version"]`)},
+			}
+
+			out, err := pkg.ToRuby()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out).To(ContainSubstring(`    aggregate sum(:updated, :format => 'Updated #{count}: %d')`))
+			Expect(out).To(ContainSubstring(`    aggregate max(:uptime)`))
+			Expect(out).To(ContainSubstring(`    aggregate stats(:duration, :percentiles => [50, 99.9])`))
+			Expect(out).To(ContainSubstring(`    aggregate summary(:'packages.*.version')`))
+
+			parsed, err := ParseRuby([]byte(out))
+			Expect(err).ToNot(HaveOccurred())
+			pact, err := parsed.ActionInterface("update")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pact.Aggregation[0].Arguments).To(MatchJSON(`["updated", {"format": "Updated #{count}: %d"}]`))
+
+			Expect(rubyLiteral(`it's C:\temp`)).To(Equal(`'it\'s C:\\temp'`))
+		})
 	})
 
 	Describe("AggregateResultJSON", func() {
@@ -164,5 +191,121 @@ var _ = Describe("McoRPC/DDL/Agent", func() {
 				},
 			}))
 		})
+
+		It("Should aggregate nested outputs and expanded arrays", func() {
+			act := &Action{
+				Name: "nested",
+				Aggregation: []ActionAggregateItem{
+					{Function: "summary", Arguments: json.RawMessage(`["packages.*.version"]`)},
+					{Function: "summary", Arguments: json.RawMessage(`["tags"]`)},
+					{Function: "summary", Arguments: json.RawMessage(`["tags.*"]`)},
+					{Function: "sum", Arguments: json.RawMessage(`["disks.#.size"]`)},
+				},
+			}
+
+			replies := []string{
+				`{"packages":{"zsh":{"version":"5.0"},"bash":{"version":"4.2"}},"tags":["web","prod"],"disks":[{"size":10},{"size":20}]}`,
+				`{"packages":{"zsh":{"version":"5.0"}},"tags":["db","prod"],"disks":[{"size":5}]}`,
+			}
+
+			for _, reply := range replies {
+				Expect(act.AggregateResultJSON([]byte(reply))).ToNot(HaveOccurred())
+			}
+
+			summary, err := act.AggregateSummaryStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(summary).To(Equal(map[string]map[string]string{
+				"packages.*.version": map[string]string{
+					"5.0": "2",
+					"4.2": "1",
+				},
+				"tags": map[string]string{
+					`["web","prod"]`: "1",
+					`["db","prod"]`:  "1",
+				},
+				"tags.*": map[string]string{
+					"web":  "1",
+					"db":   "1",
+					"prod": "2",
+				},
+				"disks.#.size": map[string]string{
+					"Sum": "35",
+				},
+			}))
+		})
+	})
+
+	Describe("AggregateSenderResultJSON", func() {
+		It("Should pass the sender to sender aware aggregators", func() {
+			act := &Action{
+				Name:        "load",
+				Aggregation: []ActionAggregateItem{{Function: "chart", Arguments: json.RawMessage(`["load", {"sort":"sender"}]`)}},
+			}
+
+			Expect(act.AggregateSenderResultJSON("node2", []byte(`{"load":2}`))).ToNot(HaveOccurred())
+			Expect(act.AggregateSenderResultJSON("node1", []byte(`{"load":1}`))).ToNot(HaveOccurred())
+
+			j, err := act.AggregateSummaryJSON()
+			Expect(err).ToNot(HaveOccurred())
+
+			res := map[string]map[string]interface{}{}
+			Expect(json.Unmarshal(j, &res)).ToNot(HaveOccurred())
+			Expect(res["load"]["series"]).To(Equal([]interface{}{
+				map[string]interface{}{"sender": "node1", "value": 1.0},
+				map[string]interface{}{"sender": "node2", "value": 2.0},
+			}))
+		})
+	})
+
+	Describe("MergeAggregates", func() {
+		It("Should merge aggregates from other actions", func() {
+			spec := []ActionAggregateItem{
+				{Function: "summary", Arguments: json.RawMessage(`["ensure"]`)},
+				{Function: "average", Arguments: json.RawMessage(`["size"]`)},
+			}
+
+			batch1 := &Action{Name: "status", Aggregation: spec}
+			batch2 := &Action{Name: "status", Aggregation: spec}
+
+			Expect(batch1.AggregateResultJSON([]byte(`{"ensure":"1.0","size":10}`))).ToNot(HaveOccurred())
+			Expect(batch2.AggregateResultJSON([]byte(`{"ensure":"1.0","size":20}`))).ToNot(HaveOccurred())
+			Expect(batch2.AggregateResultJSON([]byte(`{"ensure":"2.0","size":30}`))).ToNot(HaveOccurred())
+
+			Expect(batch1.MergeAggregates(batch2)).ToNot(HaveOccurred())
+
+			summary, err := batch1.AggregateSummaryStrings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(summary).To(Equal(map[string]map[string]string{
+				"ensure": map[string]string{"1.0": "2", "2.0": "1"},
+				"size":   map[string]string{"Average": "20.000000"},
+			}))
+		})
+
+		It("Should detect unknown aggregates in the state", func() {
+			act := &Action{Name: "status"}
+			Expect(act.MergeAggregateState([]byte(`{"ensure":{"items":{}}}`))).To(MatchError("no aggregate is defined for ensure"))
+		})
+	})
+
+	Describe("outputValues", func() {
+		It("Should find values by path", func() {
+			result := map[string]interface{}{
+				"top":    "x",
+				"a.b":    "literal",
+				"list":   []interface{}{[]interface{}{1.0, 2.0}, 3.0},
+				"nested": map[string]interface{}{"items": []interface{}{map[string]interface{}{"v": 1.0}, map[string]interface{}{"v": 2.0}}},
+			}
+
+			raw, err := json.Marshal(result)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(outputValues(result, raw, "top")).To(Equal([]interface{}{"x"}))
+			Expect(outputValues(result, raw, "a.b")).To(Equal([]interface{}{"literal"}))
+			Expect(outputValues(result, raw, "list")).To(Equal([]interface{}{[]interface{}{[]interface{}{1.0, 2.0}, 3.0}}))
+			Expect(outputValues(result, raw, "nested.items.*.v")).To(Equal([]interface{}{1.0, 2.0}))
+			Expect(outputValues(result, raw, "nested.items.1.v")).To(Equal([]interface{}{2.0}))
+			Expect(outputValues(result, raw, "nested.items.#.v")).To(Equal([]interface{}{1.0, 2.0}))
+			Expect(outputValues(result, raw, "missing.*")).To(BeEmpty())
+		})
 	})
 })
@@ -2,8 +2,15 @@ package agent
 
 import (
 	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/tidwall/gjson"
+
 	"github.com/choria-io/mcorpc-agent-provider/mcorpc/aggregate"
 )
 
@@ -13,6 +20,9 @@ type ActionAggregateItem struct {
 	Arguments json.RawMessage `json:"args"`
 }
 
+var rubyIdentifierRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+var rubyQuoteEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
 type actionAggregators struct {
 	aggregators map[string]aggregate.Aggregator
 	action      *Action
@@ -20,7 +30,9 @@ type actionAggregators struct {
 	sync.Mutex
 }
 
-// OutputName is the name of the output being aggregated
+// OutputName is the name of the output being aggregated, this can be a path into the output like
+// `packages.*.version` where `*` matches every item in a hash or array, or a gjson path
+// like `packages.#.version`
 func (a *ActionAggregateItem) OutputName() string {
 	out := []interface{}{}
 	err := json.Unmarshal(a.Arguments, &out)
@@ -36,6 +48,90 @@ func (a *ActionAggregateItem) OutputName() string {
 	return output
 }
 
+// rubyArguments renders the aggregate arguments as a Ruby argument list like `:output, :format => "%s"`
+func (a *ActionAggregateItem) rubyArguments() string {
+	args := []interface{}{}
+	err := json.Unmarshal(a.Arguments, &args)
+	if err != nil || len(args) < 1 {
+		return rubySymbol(a.OutputName())
+	}
+
+	parts := []string{rubySymbol(a.OutputName())}
+
+	if len(args) > 1 {
+		opts, ok := args[1].(map[string]interface{})
+		if ok {
+			keys := []string{}
+			for k := range opts {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				parts = append(parts, fmt.Sprintf(":%s => %s", k, rubyLiteral(opts[k])))
+			}
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// rubySymbol renders s as a Ruby symbol, quoting it when it is not a plain identifier like paths into outputs
+func rubySymbol(s string) string {
+	if rubyIdentifierRe.MatchString(s) {
+		return ":" + s
+	}
+
+	return ":" + rubyQuote(s)
+}
+
+// rubyQuote renders s as a single quoted Ruby string so that sequences like #{...} are not interpolated
+func rubyQuote(s string) string {
+	return "'" + rubyQuoteEscaper.Replace(s) + "'"
+}
+
+// rubyLiteral renders basic JSON decoded data as a Ruby literal
+func rubyLiteral(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "nil"
+
+	case string:
+		return rubyQuote(val)
+
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+
+	case bool:
+		return strconv.FormatBool(val)
+
+	case []interface{}:
+		items := []string{}
+		for _, i := range val {
+			items = append(items, rubyLiteral(i))
+		}
+
+		return "[" + strings.Join(items, ", ") + "]"
+
+	case map[string]interface{}:
+		keys := []string{}
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		items := []string{}
+		for _, k := range keys {
+			items = append(items, fmt.Sprintf("%s => %s", rubyQuote(k), rubyLiteral(val[k])))
+		}
+
+		return "{" + strings.Join(items, ", ") + "}"
+
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
 func newActionAggregators(a *Action) *actionAggregators {
 	agg := &actionAggregators{
 		action:      a,
@@ -70,16 +166,178 @@ func newActionAggregators(a *Action) *actionAggregators {
 	return agg
 }
 
-func (a *actionAggregators) aggregateItem(item string, val interface{}) {
+func (a *actionAggregators) aggregateResult(sender string, result map[string]interface{}) {
 	a.Lock()
 	defer a.Unlock()
 
-	instance, ok := a.aggregators[item]
-	if ok {
-		instance.ProcessValue(val)
+	// gjson paths need the result as JSON, it is only encoded once per result and only when needed
+	var raw []byte
+
+	for path, instance := range a.aggregators {
+		sa, senderAware := instance.(aggregate.SenderAggregator)
+
+		if raw == nil && gjsonPath(result, path) {
+			j, err := json.Marshal(result)
+			if err != nil {
+				continue
+			}
+
+			raw = j
+		}
+
+		for _, val := range outputValues(result, raw, path) {
+			if senderAware {
+				sa.ProcessSenderValue(sender, val)
+			} else {
+				instance.ProcessValue(val)
+			}
+		}
 	}
 }
 
+// gjsonPath determines if path is a gjson path rather than an output name or simple path
+func gjsonPath(result map[string]interface{}, path string) bool {
+	if _, ok := result[path]; ok {
+		return false
+	}
+
+	return strings.ContainsAny(path, "#|@?")
+}
+
+// outputValues finds all the values in result matching path, raw is the JSON encoded result
+// and is only used for gjson paths. Arrays are only expanded into individual values by `*`
+// path segments and gjson `#` queries, other values including arrays are returned as is
+func outputValues(result map[string]interface{}, raw []byte, path string) []interface{} {
+	val, ok := result[path]
+	switch {
+	case ok:
+		return []interface{}{val}
+
+	case gjsonPath(result, path):
+		res := gjson.GetBytes(raw, path)
+		if !res.Exists() {
+			return nil
+		}
+
+		if strings.Contains(path, "#") {
+			return flattenValues([]interface{}{res.Value()})
+		}
+
+		return []interface{}{res.Value()}
+
+	default:
+		return walkPath(result, strings.Split(path, "."))
+	}
+}
+
+// walkPath finds the values at path in data, a `*` segment matches all the values
+// in a hash or array while digits can index into arrays
+func walkPath(data interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		return []interface{}{data}
+	}
+
+	segment := path[0]
+	found := []interface{}{}
+
+	switch val := data.(type) {
+	case map[string]interface{}:
+		if segment == "*" {
+			keys := []string{}
+			for k := range val {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				found = append(found, walkPath(val[k], path[1:])...)
+			}
+
+			return found
+		}
+
+		item, ok := val[segment]
+		if ok {
+			found = append(found, walkPath(item, path[1:])...)
+		}
+
+	case []interface{}:
+		if segment == "*" {
+			for _, item := range val {
+				found = append(found, walkPath(item, path[1:])...)
+			}
+
+			return found
+		}
+
+		idx, err := strconv.Atoi(segment)
+		if err == nil && idx >= 0 && idx < len(val) {
+			found = append(found, walkPath(val[idx], path[1:])...)
+		}
+	}
+
+	return found
+}
+
+func flattenValues(vals []interface{}) []interface{} {
+	result := []interface{}{}
+
+	for _, v := range vals {
+		arr, ok := v.([]interface{})
+		if ok {
+			result = append(result, flattenValues(arr)...)
+			continue
+		}
+
+		result = append(result, v)
+	}
+
+	return result
+}
+
+func (a *actionAggregators) state() ([]byte, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	res := make(map[string]json.RawMessage)
+
+	for k, agg := range a.aggregators {
+		state, err := agg.State()
+		if err != nil {
+			return nil, fmt.Errorf("could not retrieve %s aggregate state for %s: %s", agg.Type(), k, err)
+		}
+
+		res[k] = state
+	}
+
+	return json.Marshal(res)
+}
+
+func (a *actionAggregators) mergeState(state []byte) error {
+	a.Lock()
+	defer a.Unlock()
+
+	states := make(map[string]json.RawMessage)
+	err := json.Unmarshal(state, &states)
+	if err != nil {
+		return fmt.Errorf("invalid aggregate state: %s", err)
+	}
+
+	for k, s := range states {
+		agg, ok := a.aggregators[k]
+		if !ok {
+			return fmt.Errorf("no aggregate is defined for %s", k)
+		}
+
+		err = agg.MergeState(s)
+		if err != nil {
+			return fmt.Errorf("could not merge %s aggregate state for %s: %s", agg.Type(), k, err)
+		}
+	}
+
+	return nil
+}
+
 func (a *actionAggregators) resultStringsFormatted() map[string][]string {
 	a.Lock()
 	defer a.Unlock()
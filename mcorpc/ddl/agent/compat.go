@@ -0,0 +1,265 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var shortVersionRe = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)$`)
+
+// Change is a difference between two versions of a DDL
+type Change struct {
+	// Action is the action the change applies to, empty for agent level changes
+	Action string `json:"action,omitempty"`
+
+	// Breaking changes can cause requests made using the older DDL to fail
+	Breaking bool `json:"breaking"`
+
+	Description string `json:"description"`
+}
+
+func (c Change) String() string {
+	kind := "additive"
+	if c.Breaking {
+		kind = "breaking"
+	}
+
+	if c.Action == "" {
+		return fmt.Sprintf("%s: %s", kind, c.Description)
+	}
+
+	return fmt.Sprintf("%s: action %s: %s", kind, c.Action, c.Description)
+}
+
+// Compatibility describes the differences between two versions of a DDL
+type Compatibility struct {
+	Changes []Change `json:"changes"`
+
+	// Unknown is the reason the compatibility could not be determined, such as versions that are not semantic versions
+	Unknown string `json:"unknown,omitempty"`
+}
+
+// Compatible is true when none of the changes are breaking
+func (c *Compatibility) Compatible() bool {
+	return len(c.Breaking()) == 0
+}
+
+// Breaking is the list of changes that can cause requests to fail
+func (c *Compatibility) Breaking() []Change {
+	return c.filter(true)
+}
+
+// Additive is the list of changes that do not affect existing requests
+func (c *Compatibility) Additive() []Change {
+	return c.filter(false)
+}
+
+func (c *Compatibility) filter(breaking bool) []Change {
+	changes := []Change{}
+
+	for _, change := range c.Changes {
+		if change.Breaking == breaking {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+func (c *Compatibility) add(action string, breaking bool, format string, a ...interface{}) {
+	c.Changes = append(c.Changes, Change{Action: action, Breaking: breaking, Description: fmt.Sprintf(format, a...)})
+}
+
+// Compare classifies the differences between this DDL and other, typically this is the DDL
+// a client uses and other is the DDL of the agent deployed on a server
+func (d *DDL) Compare(other *DDL) *Compatibility {
+	compat := &Compatibility{Changes: []Change{}}
+
+	for _, name := range d.ActionNames() {
+		if !other.HaveAction(name) {
+			compat.add(name, true, "action was removed")
+		}
+	}
+
+	for _, name := range other.ActionNames() {
+		if !d.HaveAction(name) {
+			compat.add(name, false, "action was added")
+		}
+	}
+
+	for _, act := range d.Actions {
+		oact, err := other.ActionInterface(act.Name)
+		if err != nil {
+			continue
+		}
+
+		compareInputs(compat, act, oact)
+		compareOutputs(compat, act, oact)
+	}
+
+	return compat
+}
+
+func compareInputs(compat *Compatibility, act *Action, other *Action) {
+	for _, name := range act.InputNames() {
+		input := act.Input[name]
+
+		oinput, ok := other.Input[name]
+		if !ok {
+			compat.add(act.Name, true, "input %s was removed", name)
+			continue
+		}
+
+		if !strings.EqualFold(input.Type, oinput.Type) {
+			compat.add(act.Name, true, "input %s changed type from %s to %s", name, input.Type, oinput.Type)
+		}
+
+		switch {
+		case input.Optional && !oinput.Optional:
+			compat.add(act.Name, true, "input %s is now required", name)
+		case !input.Optional && oinput.Optional:
+			compat.add(act.Name, false, "input %s is now optional", name)
+		}
+
+		// a different validation might be tighter so only removing one is considered safe
+		switch {
+		case input.Validation == oinput.Validation:
+		case oinput.Validation == "":
+			compat.add(act.Name, false, "input %s no longer validates '%s'", name, input.Validation)
+		case input.Validation == "":
+			compat.add(act.Name, true, "input %s now validates '%s'", name, oinput.Validation)
+		default:
+			compat.add(act.Name, true, "input %s changed validation from '%s' to '%s'", name, input.Validation, oinput.Validation)
+		}
+
+		switch {
+		case oinput.MaxLength > 0 && (input.MaxLength == 0 || oinput.MaxLength < input.MaxLength):
+			compat.add(act.Name, true, "input %s maximum length was reduced to %d", name, oinput.MaxLength)
+		case input.MaxLength > 0 && (oinput.MaxLength == 0 || oinput.MaxLength > input.MaxLength):
+			compat.add(act.Name, false, "input %s maximum length was increased", name)
+		}
+
+		for _, value := range stringsMissing(input.Enum, oinput.Enum) {
+			compat.add(act.Name, true, "input %s no longer accepts '%s'", name, value)
+		}
+
+		for _, value := range stringsMissing(oinput.Enum, input.Enum) {
+			compat.add(act.Name, false, "input %s now accepts '%s'", name, value)
+		}
+	}
+
+	for _, name := range other.InputNames() {
+		oinput := other.Input[name]
+
+		if _, ok := act.Input[name]; ok {
+			continue
+		}
+
+		if oinput.Optional {
+			compat.add(act.Name, false, "optional input %s was added", name)
+		} else {
+			compat.add(act.Name, true, "required input %s was added", name)
+		}
+	}
+}
+
+func compareOutputs(compat *Compatibility, act *Action, other *Action) {
+	for _, name := range act.OutputNames() {
+		ooutput, ok := other.Output[name]
+		if !ok {
+			compat.add(act.Name, true, "output %s was removed", name)
+			continue
+		}
+
+		if !strings.EqualFold(act.Output[name].Type, ooutput.Type) {
+			compat.add(act.Name, true, "output %s changed type from %s to %s", name, act.Output[name].Type, ooutput.Type)
+		}
+	}
+
+	for _, name := range other.OutputNames() {
+		if _, ok := act.Output[name]; !ok {
+			compat.add(act.Name, false, "output %s was added", name)
+		}
+	}
+}
+
+// stringsMissing is the sorted list of items in a that are not in b
+func stringsMissing(a []string, b []string) []string {
+	missing := []string{}
+
+	for _, i := range a {
+		found := false
+
+		for _, j := range b {
+			if i == j {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, i)
+		}
+	}
+
+	sort.Strings(missing)
+
+	return missing
+}
+
+// CompareVersion classifies the difference between the semantic version of this DDL and
+// version, typically the version of the agent a server reports in rpcutil#agent_inventory.
+// A different major version or an older minor version is considered breaking, versions that
+// cannot be parsed are reported as Unknown rather than as a change
+func (d *DDL) CompareVersion(version string) *Compatibility {
+	compat := &Compatibility{Changes: []Change{}}
+
+	mine, err := semVerParts(d.Metadata.Version)
+	if err != nil {
+		compat.Unknown = fmt.Sprintf("cannot compare versions: %s", err)
+		return compat
+	}
+
+	theirs, err := semVerParts(version)
+	if err != nil {
+		compat.Unknown = fmt.Sprintf("cannot compare versions: %s", err)
+		return compat
+	}
+
+	switch {
+	case mine[0] != theirs[0]:
+		compat.add("", true, "major version changed from %s to %s", d.Metadata.Version, version)
+	case theirs[1] < mine[1]:
+		compat.add("", true, "version %s is older than %s", version, d.Metadata.Version)
+	case theirs[1] > mine[1] || (theirs[1] == mine[1] && theirs[2] > mine[2]):
+		compat.add("", false, "version %s is newer than %s", version, d.Metadata.Version)
+	}
+
+	return compat
+}
+
+// semVerParts parses the major, minor and patch parts of version, two part versions like 1.0
+// found in older DDLs are treated as having a patch version of 0
+func semVerParts(version string) ([3]int, error) {
+	parts := [3]int{}
+
+	match := semverRe.FindStringSubmatch(version)
+	if match == nil {
+		match = shortVersionRe.FindStringSubmatch(version)
+	}
+
+	if match == nil {
+		return parts, fmt.Errorf("%s is not a valid semantic version", version)
+	}
+
+	for i := range parts {
+		if i+1 < len(match) {
+			parts[i], _ = strconv.Atoi(match[i+1])
+		}
+	}
+
+	return parts, nil
+}
@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("McoRPC/DDL/Agent/Compatibility", func() {
+	var client, server *DDL
+
+	BeforeEach(func() {
+		var err error
+
+		client, err = New(filepath.Join("testdata", "mcollective", "agent", "package.json"))
+		Expect(err).ToNot(HaveOccurred())
+
+		server, err = New(filepath.Join("testdata", "mcollective", "agent", "package.json"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	descriptions := func(changes []Change) []string {
+		res := []string{}
+		for _, c := range changes {
+			res = append(res, c.String())
+		}
+
+		return res
+	}
+
+	Describe("Compare", func() {
+		It("Should find no changes in identical DDLs", func() {
+			compat := client.Compare(server)
+			Expect(compat.Changes).To(BeEmpty())
+			Expect(compat.Compatible()).To(BeTrue())
+		})
+
+		It("Should detect breaking changes", func() {
+			actions := []*Action{}
+			for _, act := range server.Actions {
+				if act.Name != "md5" {
+					actions = append(actions, act)
+				}
+			}
+			server.Actions = actions
+
+			install, err := server.ActionInterface("install")
+			Expect(err).ToNot(HaveOccurred())
+			install.Input["version"].Optional = false
+			install.Input["package"].MaxLength = 50
+			install.Input["force"] = &ActionInputItem{Type: "boolean"}
+			delete(install.Output, "epoch")
+
+			clean, err := server.ActionInterface("yum_clean")
+			Expect(err).ToNot(HaveOccurred())
+			clean.Input["mode"].Enum = []string{"all", "metadata"}
+			clean.Input["mode"].Validation = "shellsafe"
+			clean.Output["exitcode"].Type = "string"
+
+			cclean, err := client.ActionInterface("yum_clean")
+			Expect(err).ToNot(HaveOccurred())
+			cclean.Output["exitcode"].Type = "integer"
+
+			compat := client.Compare(server)
+			Expect(compat.Compatible()).To(BeFalse())
+			Expect(compat.Additive()).To(BeEmpty())
+			Expect(descriptions(compat.Breaking())).To(Equal([]string{
+				"breaking: action md5: action was removed",
+				"breaking: action install: input package maximum length was reduced to 50",
+				"breaking: action install: input version is now required",
+				"breaking: action install: required input force was added",
+				"breaking: action install: output epoch was removed",
+				"breaking: action yum_clean: input mode now validates 'shellsafe'",
+				"breaking: action yum_clean: input mode no longer accepts 'dbcache'",
+				"breaking: action yum_clean: input mode no longer accepts 'expire-cache'",
+				"breaking: action yum_clean: input mode no longer accepts 'headers'",
+				"breaking: action yum_clean: input mode no longer accepts 'packages'",
+				"breaking: action yum_clean: input mode no longer accepts 'plugins'",
+				"breaking: action yum_clean: output exitcode changed type from integer to string",
+			}))
+		})
+
+		It("Should detect additive changes", func() {
+			server.Actions = append(server.Actions, &Action{Name: "downgrade"})
+
+			install, err := server.ActionInterface("install")
+			Expect(err).ToNot(HaveOccurred())
+			install.Input["force"] = &ActionInputItem{Type: "boolean", Optional: true}
+			install.Input["package"].Optional = true
+			install.Output["summary"] = &ActionOutputItem{Type: "string"}
+			install.Input["version"].Validation = ""
+
+			compat := client.Compare(server)
+			Expect(compat.Compatible()).To(BeTrue())
+			Expect(descriptions(compat.Additive())).To(Equal([]string{
+				"additive: action downgrade: action was added",
+				"additive: action install: input package is now optional",
+				"additive: action install: input version no longer validates 'shellsafe'",
+				"additive: action install: optional input force was added",
+				"additive: action install: output summary was added",
+			}))
+		})
+	})
+
+	Describe("CompareVersion", func() {
+		It("Should compare semantic versions", func() {
+			Expect(client.CompareVersion("5.0.0").Changes).To(BeEmpty())
+			Expect(descriptions(client.CompareVersion("5.1.2").Changes)).To(Equal([]string{"additive: version 5.1.2 is newer than 5.0.0"}))
+			Expect(descriptions(client.CompareVersion("6.0.0").Changes)).To(Equal([]string{"breaking: major version changed from 5.0.0 to 6.0.0"}))
+
+			client.Metadata.Version = "5.2.0"
+			Expect(descriptions(client.CompareVersion("5.1.9").Changes)).To(Equal([]string{"breaking: version 5.1.9 is older than 5.2.0"}))
+
+			client.Metadata.Version = "1.0"
+			Expect(descriptions(client.CompareVersion("1.1").Changes)).To(Equal([]string{"additive: version 1.1 is newer than 1.0"}))
+			Expect(descriptions(client.CompareVersion("2.0.0").Changes)).To(Equal([]string{"breaking: major version changed from 1.0 to 2.0.0"}))
+			Expect(client.CompareVersion("1.0.0").Changes).To(BeEmpty())
+
+			compat := client.CompareVersion("5.x")
+			Expect(compat.Changes).To(BeEmpty())
+			Expect(compat.Compatible()).To(BeTrue())
+			Expect(compat.Unknown).To(Equal("cannot compare versions: 5.x is not a valid semantic version"))
+		})
+	})
+})
@@ -0,0 +1,296 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htemplate "html/template"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+type docInput struct {
+	Name        string
+	Prompt      string
+	Description string
+	Type        string
+	Required    bool
+	Validation  string
+	Default     string
+}
+
+type docOutput struct {
+	Name        string
+	DisplayAs   string
+	Description string
+	Type        string
+	Default     string
+}
+
+type docAggregate struct {
+	Function string
+	Output   string
+	Options  string
+}
+
+type docAction struct {
+	Name        string
+	Description string
+	Display     string
+	Inputs      []docInput
+	Outputs     []docOutput
+	Aggregates  []docAggregate
+}
+
+type docAgent struct {
+	*DDL
+	Actions []docAction
+}
+
+// ToMarkdown generates Markdown documentation for the agent, its actions, their inputs, outputs and aggregations
+func (d *DDL) ToMarkdown() (string, error) {
+	var out bytes.Buffer
+
+	funcs := template.FuncMap{
+		"cell": markdownCell,
+		"code": func(v string) string {
+			if v == "" {
+				return ""
+			}
+
+			return "`" + v + "`"
+		},
+	}
+
+	tpl := template.Must(template.New(d.Metadata.Name).Funcs(funcs).Parse(markdownDocTemplate))
+	err := tpl.Execute(&out, d.docs())
+
+	return out.String(), err
+}
+
+// ToHTML generates a standalone HTML document with the same content as ToMarkdown
+func (d *DDL) ToHTML() (string, error) {
+	var out bytes.Buffer
+
+	tpl := htemplate.Must(htemplate.New(d.Metadata.Name).Parse(htmlDocTemplate))
+	err := tpl.Execute(&out, d.docs())
+
+	return out.String(), err
+}
+
+func (d *DDL) docs() docAgent {
+	agent := docAgent{DDL: d}
+
+	for _, act := range d.Actions {
+		action := docAction{
+			Name:        act.Name,
+			Description: act.Description,
+			Display:     act.Display,
+		}
+
+		for _, name := range act.InputNames() {
+			input := act.Input[name]
+
+			action.Inputs = append(action.Inputs, docInput{
+				Name:        name,
+				Prompt:      input.Prompt,
+				Description: input.Description,
+				Type:        input.Type,
+				Required:    !input.Optional,
+				Validation:  strings.Join(input.Constraints(), ", "),
+				Default:     docDefault(input.Default),
+			})
+		}
+
+		for _, name := range act.OutputNames() {
+			output := act.Output[name]
+
+			action.Outputs = append(action.Outputs, docOutput{
+				Name:        name,
+				DisplayAs:   output.DisplayAs,
+				Description: output.Description,
+				Type:        output.Type,
+				Default:     docDefault(output.Default),
+			})
+		}
+
+		for _, agg := range act.Aggregation {
+			action.Aggregates = append(action.Aggregates, docAggregate{
+				Function: agg.Function,
+				Output:   agg.OutputName(),
+				Options:  agg.docOptions(),
+			})
+		}
+
+		agent.Actions = append(agent.Actions, action)
+	}
+
+	return agent
+}
+
+// docOptions renders the aggregate options as sorted key=value pairs
+func (a *ActionAggregateItem) docOptions() string {
+	args := []interface{}{}
+	err := json.Unmarshal(a.Arguments, &args)
+	if err != nil || len(args) < 2 {
+		return ""
+	}
+
+	opts, ok := args[1].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	keys := []string{}
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := []string{}
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", k, docDefault(opts[k])))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func docDefault(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	j, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return string(j)
+}
+
+// markdownCell escapes v so that it can be used in a Markdown table cell
+func markdownCell(v string) string {
+	v = strings.Replace(v, "|", `\|`, -1)
+	v = strings.Replace(v, "\r\n", " ", -1)
+
+	return strings.Replace(v, "\n", " ", -1)
+}
+
+var markdownDocTemplate = `# {{ .Metadata.Name }} agent
+
+{{ .Metadata.Description }}
+
+| Property  | Value |
+|-----------|-------|
+| Author    | {{ cell .Metadata.Author }} |
+| Version   | {{ cell .Metadata.Version }} |
+| License   | {{ cell .Metadata.License }} |
+| Timeout   | {{ .Metadata.Timeout }} seconds |
+| Home Page | {{ cell .Metadata.URL }} |
+{{- if .Metadata.Provider }}
+| Provider  | {{ cell .Metadata.Provider }} |
+{{- end }}
+
+## Actions
+{{ range $action := .Actions }}
+### {{ $action.Name }}
+
+{{ $action.Description }}
+
+Results are displayed: **{{ $action.Display }}**
+{{ if $action.Inputs }}
+#### Inputs
+
+| Input | Prompt | Description | Type | Required | Validation | Default |
+|-------|--------|-------------|------|----------|------------|---------|
+{{- range $input := $action.Inputs }}
+| ` + "`{{ $input.Name }}`" + ` | {{ cell $input.Prompt }} | {{ cell $input.Description }} | {{ $input.Type }} | {{ if $input.Required }}yes{{ else }}no{{ end }} | {{ cell $input.Validation | code }} | {{ cell $input.Default | code }} |
+{{- end }}
+{{ end }}
+{{- if $action.Outputs }}
+#### Outputs
+
+| Output | Display As | Description | Type | Default |
+|--------|------------|-------------|------|---------|
+{{- range $output := $action.Outputs }}
+| ` + "`{{ $output.Name }}`" + ` | {{ cell $output.DisplayAs }} | {{ cell $output.Description }} | {{ $output.Type }} | {{ cell $output.Default | code }} |
+{{- end }}
+{{ end }}
+{{- if $action.Aggregates }}
+#### Aggregations
+
+| Function | Output | Options |
+|----------|--------|---------|
+{{- range $agg := $action.Aggregates }}
+| {{ $agg.Function }} | ` + "`{{ $agg.Output }}`" + ` | {{ cell $agg.Options }} |
+{{- end }}
+{{ end }}
+{{- end }}`
+
+var htmlDocTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{ .Metadata.Name }} agent</title>
+  <style>
+    body { font-family: sans-serif; margin: 2em auto; max-width: 60em; }
+    table { border-collapse: collapse; margin-bottom: 1em; }
+    th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+    th { background: #eee; }
+  </style>
+</head>
+<body>
+  <h1>{{ .Metadata.Name }} agent</h1>
+  <p>{{ .Metadata.Description }}</p>
+
+  <table>
+    <tr><th>Author</th><td>{{ .Metadata.Author }}</td></tr>
+    <tr><th>Version</th><td>{{ .Metadata.Version }}</td></tr>
+    <tr><th>License</th><td>{{ .Metadata.License }}</td></tr>
+    <tr><th>Timeout</th><td>{{ .Metadata.Timeout }} seconds</td></tr>
+    <tr><th>Home Page</th><td><a href="{{ .Metadata.URL }}">{{ .Metadata.URL }}</a></td></tr>
+{{- if .Metadata.Provider }}
+    <tr><th>Provider</th><td>{{ .Metadata.Provider }}</td></tr>
+{{- end }}
+  </table>
+
+  <h2>Actions</h2>
+{{ range $action := .Actions }}
+  <h3 id="action-{{ $action.Name }}">{{ $action.Name }}</h3>
+  <p>{{ $action.Description }}</p>
+  <p>Results are displayed: <strong>{{ $action.Display }}</strong></p>
+{{- if $action.Inputs }}
+
+  <h4>Inputs</h4>
+  <table>
+    <tr><th>Input</th><th>Prompt</th><th>Description</th><th>Type</th><th>Required</th><th>Validation</th><th>Default</th></tr>
+{{- range $input := $action.Inputs }}
+    <tr><td><code>{{ $input.Name }}</code></td><td>{{ $input.Prompt }}</td><td>{{ $input.Description }}</td><td>{{ $input.Type }}</td><td>{{ if $input.Required }}yes{{ else }}no{{ end }}</td><td>{{ if $input.Validation }}<code>{{ $input.Validation }}</code>{{ end }}</td><td>{{ if $input.Default }}<code>{{ $input.Default }}</code>{{ end }}</td></tr>
+{{- end }}
+  </table>
+{{- end }}
+{{- if $action.Outputs }}
+
+  <h4>Outputs</h4>
+  <table>
+    <tr><th>Output</th><th>Display As</th><th>Description</th><th>Type</th><th>Default</th></tr>
+{{- range $output := $action.Outputs }}
+    <tr><td><code>{{ $output.Name }}</code></td><td>{{ $output.DisplayAs }}</td><td>{{ $output.Description }}</td><td>{{ $output.Type }}</td><td>{{ if $output.Default }}<code>{{ $output.Default }}</code>{{ end }}</td></tr>
+{{- end }}
+  </table>
+{{- end }}
+{{- if $action.Aggregates }}
+
+  <h4>Aggregations</h4>
+  <table>
+    <tr><th>Function</th><th>Output</th><th>Options</th></tr>
+{{- range $agg := $action.Aggregates }}
+    <tr><td>{{ $agg.Function }}</td><td><code>{{ $agg.Output }}</code></td><td>{{ $agg.Options }}</td></tr>
+{{- end }}
+  </table>
+{{- end }}
+{{ end }}
+</body>
+</html>
+`
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("McoRPC/DDL/Agent/Docs", func() {
+	var ddl *DDL
+
+	BeforeEach(func() {
+		var err error
+
+		ddl, err = New(filepath.Join("testdata", "ruby", "mcollective", "agent", "service.ddl"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("ToMarkdown", func() {
+		It("Should document the agent", func() {
+			md, err := ddl.ToMarkdown()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md).To(HavePrefix("# service agent\n\nStart and stop system services\n"))
+			Expect(md).To(ContainSubstring("| Timeout   | 60 seconds |"))
+			Expect(md).To(ContainSubstring("### restart\n\nRestart a service\n\nResults are displayed: **failed**"))
+			Expect(md).To(ContainSubstring("| `signal` | Signal | The signal to send | list | no | `one of HUP, TERM` | `\"TERM\"` |"))
+			Expect(md).To(ContainSubstring("| `service` | Service Name | The service to get the status for | string | yes | `^[a-zA-Z\\-_\\d.@]+$, max length 90` |  |"))
+			Expect(md).To(ContainSubstring("| `duration` | Duration | Time taken to restart | float |  |"))
+			Expect(md).To(ContainSubstring("| stats | `duration` | format=\"%.2f\", percentiles=[50,90] |"))
+		})
+
+		It("Should escape table cells", func() {
+			ddl.Actions[0].Output["status"].Description = "running | stopped"
+
+			md, err := ddl.ToMarkdown()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(md).To(ContainSubstring(`| running \| stopped |`))
+		})
+	})
+
+	Describe("ToHTML", func() {
+		It("Should produce an escaped standalone document", func() {
+			ddl.Metadata.Description = "Start & stop <services>"
+
+			html, err := ddl.ToHTML()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(html).To(HavePrefix("<!DOCTYPE html>"))
+			Expect(html).To(ContainSubstring("<title>service agent</title>"))
+			Expect(html).To(ContainSubstring("<p>Start &amp; stop &lt;services&gt;</p>"))
+			Expect(html).To(ContainSubstring(`<h3 id="action-restart">restart</h3>`))
+			Expect(html).To(ContainSubstring("<tr><td>stats</td><td><code>duration</code></td><td>format=&#34;%.2f&#34;, percentiles=[50,90]</td></tr>"))
+			Expect(html).To(HaveSuffix("</html>\n"))
+		})
+	})
+})
@@ -0,0 +1,303 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+type goField struct {
+	Name        string
+	JSONName    string
+	Type        string
+	Description string
+	Optional    bool
+	Validation  string
+}
+
+type goAction struct {
+	Name        string
+	Method      string
+	Description string
+	Inputs      []goField
+	Outputs     []goField
+}
+
+type goClient struct {
+	Package string
+	Agent   string
+	DDL     *DDL
+	Actions []goAction
+}
+
+var goInitialisms = map[string]bool{"api": true, "cpu": true, "dns": true, "http": true, "id": true, "ip": true, "json": true, "md5": true, "os": true, "sha": true, "ssh": true, "tcp": true, "udp": true, "uid": true, "url": true, "uuid": true}
+
+var goIdentifierSplitRe = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// ToGoClient generates the source for a typed Go client package called pkg for the agent
+//
+// The package has a Client with a method per action that takes a typed input struct and
+// calls a handler with every reply decoded into a typed output struct
+func (d *DDL) ToGoClient(pkg string) (string, error) {
+	if !token.IsIdentifier(pkg) || pkg == "_" {
+		return "", fmt.Errorf("'%s' is not a valid Go package name", pkg)
+	}
+
+	client := goClient{
+		Package: pkg,
+		Agent:   d.Metadata.Name,
+		DDL:     d,
+	}
+
+	methods := map[string]string{}
+
+	for _, act := range d.Actions {
+		action := goAction{
+			Name:        act.Name,
+			Method:      goIdentifier(act.Name),
+			Description: goComment(act.Description),
+		}
+
+		if other, ok := methods[action.Method]; ok {
+			return "", fmt.Errorf("actions %s and %s both generate the method %s", other, act.Name, action.Method)
+		}
+		methods[action.Method] = act.Name
+
+		var err error
+
+		action.Inputs, err = goInputFields(act)
+		if err != nil {
+			return "", fmt.Errorf("could not generate inputs for action %s: %s", act.Name, err)
+		}
+
+		action.Outputs, err = goOutputFields(act)
+		if err != nil {
+			return "", fmt.Errorf("could not generate outputs for action %s: %s", act.Name, err)
+		}
+
+		client.Actions = append(client.Actions, action)
+	}
+
+	var out bytes.Buffer
+
+	funcs := template.FuncMap{
+		"comment": goComment,
+	}
+
+	tpl := template.Must(template.New(d.Metadata.Name).Funcs(funcs).Parse(goClientTemplate))
+	err := tpl.Execute(&out, client)
+	if err != nil {
+		return "", err
+	}
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("could not format generated client: %s", err)
+	}
+
+	return string(src), nil
+}
+
+func goInputFields(act *Action) ([]goField, error) {
+	fields := []goField{}
+	seen := map[string]string{}
+
+	for _, name := range act.InputNames() {
+		input := act.Input[name]
+
+		field := goField{
+			Name:        goIdentifier(name),
+			JSONName:    name,
+			Type:        goInputType(input),
+			Description: goComment(input.Description),
+			Optional:    input.Optional,
+			Validation:  goComment(strings.Join(input.Constraints(), ", ")),
+		}
+
+		if other, ok := seen[field.Name]; ok {
+			return nil, fmt.Errorf("%s and %s both generate the field %s", other, name, field.Name)
+		}
+		seen[field.Name] = name
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func goOutputFields(act *Action) ([]goField, error) {
+	fields := []goField{}
+	seen := map[string]string{}
+
+	for _, name := range act.OutputNames() {
+		output := act.Output[name]
+
+		field := goField{
+			Name:        goIdentifier(name),
+			JSONName:    name,
+			Type:        goType(output.Type),
+			Description: goComment(output.Description),
+		}
+
+		if other, ok := seen[field.Name]; ok {
+			return nil, fmt.Errorf("%s and %s both generate the field %s", other, name, field.Name)
+		}
+		seen[field.Name] = name
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// goType is the Go type used to represent a DDL type, untyped outputs are interface{}
+func goType(t string) string {
+	switch t {
+	case "string", "list":
+		return "string"
+	case "integer":
+		return "int64"
+	case "float", "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "hash", "Hash":
+		return "map[string]interface{}"
+	case "array", "Array":
+		return "[]interface{}"
+	default:
+		return "interface{}"
+	}
+}
+
+// goInputType is the Go type for an input, arrays with described items are typed slices
+func goInputType(i *ActionInputItem) string {
+	if i.Items != nil && strings.ToLower(i.Type) == "array" {
+		return "[]" + goInputType(i.Items)
+	}
+
+	return goType(i.Type)
+}
+
+// goIdentifier turns names like package_name into exported Go identifiers like PackageName
+func goIdentifier(name string) string {
+	var out strings.Builder
+
+	for _, part := range goIdentifierSplitRe.Split(name, -1) {
+		if part == "" {
+			continue
+		}
+
+		if goInitialisms[strings.ToLower(part)] {
+			out.WriteString(strings.ToUpper(part))
+			continue
+		}
+
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		out.WriteString(string(runes))
+	}
+
+	id := out.String()
+	if id == "" || unicode.IsDigit([]rune(id)[0]) {
+		id = "X" + id
+	}
+
+	return id
+}
+
+// goComment makes s safe to use on a single comment line
+func goComment(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var goClientTemplate = `// Code generated from the {{ .Agent }} agent DDL. DO NOT EDIT.
+
+// Package {{ .Package }} is a typed client for the {{ .Agent }} agent
+//
+// {{ comment .DDL.Metadata.Description }}
+package {{ .Package }}
+
+import (
+{{- if .Actions }}
+	"context"
+	"encoding/json"
+
+	"github.com/choria-io/go-protocol/protocol"
+	"github.com/choria-io/mcorpc-agent-provider/mcorpc"
+{{- end }}
+	rpc "github.com/choria-io/mcorpc-agent-provider/mcorpc/client"
+)
+
+// Client is a typed client for the {{ .Agent }} agent
+type Client struct {
+	rpc *rpc.RPC
+}
+
+// New creates a new client for the {{ .Agent }} agent
+func New(fw rpc.ChoriaFramework, opts ...rpc.Option) (*Client, error) {
+	c, err := rpc.New(fw, "{{ .Agent }}", opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{rpc: c}, nil
+}
+{{ range $action := .Actions }}
+// {{ $action.Method }}Input is the input to the {{ $action.Name }} action
+type {{ $action.Method }}Input struct {
+{{- range $field := $action.Inputs }}
+	// {{ $field.Name }}: {{ $field.Description }} ({{ if $field.Optional }}optional{{ else }}required{{ end }}{{ if $field.Validation }}, {{ $field.Validation }}{{ end }})
+	{{ $field.Name }} {{ $field.Type }} ` + "`" + `json:"{{ $field.JSONName }}{{ if $field.Optional }},omitempty{{ end }}"` + "`" + `
+{{- end }}
+}
+
+// {{ $action.Method }}Output is the data returned by the {{ $action.Name }} action
+type {{ $action.Method }}Output struct {
+{{- range $field := $action.Outputs }}
+	// {{ $field.Name }}: {{ $field.Description }}
+	{{ $field.Name }} {{ $field.Type }} ` + "`" + `json:"{{ $field.JSONName }}"` + "`" + `
+{{- end }}
+}
+
+// {{ $action.Method }}Result is a reply received from a node for the {{ $action.Name }} action
+type {{ $action.Method }}Result struct {
+	Sender     string
+	Statuscode mcorpc.StatusCode
+	Statusmsg  string
+	Data       {{ $action.Method }}Output
+
+	// DecodeError is set when the reply data could not be decoded into Data
+	DecodeError error
+}
+
+// {{ $action.Method }} performs the {{ $action.Name }} action: {{ $action.Description }}
+//
+// The handler is called for every reply received, any ReplyHandler given in opts is replaced
+func (c *Client) {{ $action.Method }}(ctx context.Context, input {{ $action.Method }}Input, handler func(*{{ $action.Method }}Result), opts ...rpc.RequestOption) (rpc.RequestResult, error) {
+	opts = append(opts, rpc.ReplyHandler(func(pr protocol.Reply, r *rpc.RPCReply) {
+		if handler == nil {
+			return
+		}
+
+		res := &{{ $action.Method }}Result{
+			Sender:     pr.SenderID(),
+			Statuscode: r.Statuscode,
+			Statusmsg:  r.Statusmsg,
+		}
+
+		if len(r.Data) > 0 {
+			res.DecodeError = json.Unmarshal(r.Data, &res.Data)
+		}
+
+		handler(res)
+	}))
+
+	return c.rpc.Do(ctx, "{{ $action.Name }}", input, opts...)
+}
+{{ end }}`
@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// goClientStubs mirror the parts of the packages used by generated clients so they can be
+// type checked without a Go toolchain or module cache
+var goClientStubs = map[string]string{
+	"context": `package context
+type Context interface{}`,
+
+	"encoding/json": `package json
+type RawMessage []byte
+func Unmarshal(data []byte, v interface{}) error { return nil }`,
+
+	"github.com/choria-io/go-protocol/protocol": `package protocol
+type Reply interface{ SenderID() string }`,
+
+	"github.com/choria-io/mcorpc-agent-provider/mcorpc": `package mcorpc
+type StatusCode uint8`,
+
+	"github.com/choria-io/mcorpc-agent-provider/mcorpc/client": `package client
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/choria-io/go-protocol/protocol"
+	"github.com/choria-io/mcorpc-agent-provider/mcorpc"
+)
+type ChoriaFramework interface{}
+type RPC struct{}
+type Option func(r *RPC)
+type RequestOptions struct{}
+type RequestOption func(*RequestOptions)
+type RequestResult interface{}
+type RPCReply struct {
+	Statuscode mcorpc.StatusCode
+	Statusmsg  string
+	Data       json.RawMessage
+}
+type Handler func(protocol.Reply, *RPCReply)
+func New(fw ChoriaFramework, agent string, opts ...Option) (*RPC, error) { return nil, nil }
+func ReplyHandler(f Handler) RequestOption { return nil }
+func (r *RPC) Do(ctx context.Context, action string, payload interface{}, opts ...RequestOption) (RequestResult, error) { return nil, nil }`,
+}
+
+type goClientStubImporter struct {
+	fset *token.FileSet
+	pkgs map[string]*types.Package
+}
+
+func (i *goClientStubImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := i.pkgs[path]; ok {
+		return pkg, nil
+	}
+
+	src, ok := goClientStubs[path]
+	if !ok {
+		return nil, fmt.Errorf("no stub for package %s", path)
+	}
+
+	f, err := parser.ParseFile(i.fset, path+".go", src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg, err := (&types.Config{Importer: i}).Check(path, i.fset, []*ast.File{f}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	i.pkgs[path] = pkg
+
+	return pkg, nil
+}
+
+var _ = Describe("McoRPC/DDL/Agent/GoClient", func() {
+	// typeCheck parses and type checks the generated client against the package stubs
+	typeCheck := func(src string) {
+		fset := token.NewFileSet()
+
+		f, err := parser.ParseFile(fset, "client.go", src, 0)
+		Expect(err).ToNot(HaveOccurred())
+
+		conf := &types.Config{Importer: &goClientStubImporter{fset: fset, pkgs: make(map[string]*types.Package)}}
+		_, err = conf.Check(f.Name.Name, fset, []*ast.File{f}, nil)
+		Expect(err).ToNot(HaveOccurred(), src)
+	}
+
+	Describe("ToGoClient", func() {
+		It("Should generate a valid typed client", func() {
+			ddl, err := New(filepath.Join("testdata", "ruby", "mcollective", "agent", "service.ddl"))
+			Expect(err).ToNot(HaveOccurred())
+
+			src, err := ddl.ToGoClient("serviceclient")
+			Expect(err).ToNot(HaveOccurred())
+
+			f, err := parser.ParseFile(token.NewFileSet(), "client.go", src, parser.ParseComments)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.Name.Name).To(Equal("serviceclient"))
+
+			Expect(src).To(ContainSubstring(`c, err := rpc.New(fw, "service", opts...)`))
+			Expect(src).To(ContainSubstring("// Signal: The signal to send (optional, one of HUP, TERM)\n\tSignal string `json:\"signal,omitempty\"`"))
+			Expect(src).To(ContainSubstring("Service string `json:\"service\"`"))
+			Expect(src).To(ContainSubstring("Duration float64 `json:\"duration\"`"))
+			Expect(src).To(ContainSubstring("Status interface{} `json:\"status\"`"))
+			Expect(src).To(ContainSubstring("func (c *Client) Restart(ctx context.Context, input RestartInput, handler func(*RestartResult), opts ...rpc.RequestOption) (rpc.RequestResult, error) {"))
+			Expect(src).To(ContainSubstring(`return c.rpc.Do(ctx, "restart", input, opts...)`))
+
+			typeCheck(src)
+		})
+
+		It("Should only import packages that are used", func() {
+			ddl, err := New(filepath.Join("testdata", "ruby", "mcollective", "agent", "service.ddl"))
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, act := range ddl.Actions {
+				act.Input = map[string]*ActionInputItem{}
+				act.Output = map[string]*ActionOutputItem{}
+			}
+
+			src, err := ddl.ToGoClient("serviceclient")
+			Expect(err).ToNot(HaveOccurred())
+			typeCheck(src)
+
+			ddl.Actions = nil
+
+			src, err = ddl.ToGoClient("serviceclient")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(src).ToNot(ContainSubstring(`"context"`))
+			typeCheck(src)
+		})
+
+		It("Should detect conflicting names", func() {
+			ddl, err := New(filepath.Join("testdata", "ruby", "mcollective", "agent", "service.ddl"))
+			Expect(err).ToNot(HaveOccurred())
+
+			ddl.Actions[0].Input["service-name"] = ddl.Actions[0].Input["service"]
+			ddl.Actions[0].Input["service_name"] = ddl.Actions[0].Input["service"]
+
+			_, err = ddl.ToGoClient("serviceclient")
+			Expect(err).To(MatchError("could not generate inputs for action status: service-name and service_name both generate the field ServiceName"))
+		})
+
+		It("Should detect invalid package names", func() {
+			ddl, err := New(filepath.Join("testdata", "ruby", "mcollective", "agent", "service.ddl"))
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, pkg := range []string{"", "service-client", "1client", "type", "_"} {
+				_, err = ddl.ToGoClient(pkg)
+				Expect(err).To(MatchError(fmt.Sprintf("'%s' is not a valid Go package name", pkg)))
+			}
+		})
+	})
+
+	Describe("goIdentifier", func() {
+		It("Should produce exported identifiers", func() {
+			Expect(goIdentifier("status")).To(Equal("Status"))
+			Expect(goIdentifier("apt_checkupdates")).To(Equal("AptCheckupdates"))
+			Expect(goIdentifier("package_id")).To(Equal("PackageID"))
+			Expect(goIdentifier("source-url")).To(Equal("SourceURL"))
+			Expect(goIdentifier("64bit")).To(Equal("X64bit"))
+		})
+	})
+})
@@ -0,0 +1,235 @@
+package agent
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// shellsafePattern matches strings accepted by the shellsafe validator, && is rejected
+// without lookahead so that the pattern works with RE2 based validators too
+const shellsafePattern = "^[^`$;|<>&]*(&[^`$;|<>&]+)*&?$"
+
+var namedValidatorRe = regexp.MustCompile("^[a-z]")
+
+// InputSchema is a JSON Schema document describing the request data for the action
+func (a *Action) InputSchema() ([]byte, error) {
+	schema := a.inputSchema()
+	schema["$schema"] = jsonSchemaDraft
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// OutputSchema is a JSON Schema document describing the reply data for the action
+func (a *Action) OutputSchema() ([]byte, error) {
+	schema := a.outputSchema()
+	schema["$schema"] = jsonSchemaDraft
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// JSONSchema is a JSON Schema document bundling the input and output schemas of every action,
+// the schemas for an action can be referenced using #/definitions/<action>/definitions/input
+// and #/definitions/<action>/definitions/output
+func (d *DDL) JSONSchema() ([]byte, error) {
+	actions := make(map[string]interface{})
+
+	for _, act := range d.Actions {
+		actions[act.Name] = map[string]interface{}{
+			"title":       act.Name,
+			"description": act.Description,
+			"definitions": map[string]interface{}{
+				"input":  act.inputSchema(),
+				"output": act.outputSchema(),
+			},
+		}
+	}
+
+	schema := map[string]interface{}{
+		"$schema":     jsonSchemaDraft,
+		"title":       d.Metadata.Name + " agent",
+		"description": d.Metadata.Description,
+		"definitions": actions,
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func (a *Action) inputSchema() map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for _, name := range a.InputNames() {
+		input := a.Input[name]
+		properties[name] = input.jsonSchema()
+
+		if !input.Optional {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"title":                a.Name + " input",
+		"description":          a.Description,
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func (a *Action) outputSchema() map[string]interface{} {
+	properties := make(map[string]interface{})
+
+	for _, name := range a.OutputNames() {
+		output := a.Output[name]
+
+		prop := map[string]interface{}{
+			"title":       output.DisplayAs,
+			"description": output.Description,
+		}
+
+		jsonSchemaType(prop, output.Type)
+
+		if output.Default != nil {
+			prop["default"] = output.Default
+		}
+
+		properties[name] = prop
+	}
+
+	return map[string]interface{}{
+		"title":       a.Name + " output",
+		"description": a.Description,
+		"type":        "object",
+		"properties":  properties,
+	}
+}
+
+func (i *ActionInputItem) jsonSchema() map[string]interface{} {
+	prop := make(map[string]interface{})
+
+	if i.Prompt != "" {
+		prop["title"] = i.Prompt
+	}
+
+	if i.Description != "" {
+		prop["description"] = i.Description
+	}
+
+	jsonSchemaType(prop, i.Type)
+
+	switch strings.ToLower(i.Type) {
+	case "list":
+		prop["enum"] = i.Enum
+
+	case "string":
+		if i.MinLength > 0 {
+			prop["minLength"] = i.MinLength
+		}
+
+		if i.MaxLength > 0 {
+			prop["maxLength"] = i.MaxLength
+		}
+
+		jsonSchemaValidation(prop, i.Validation)
+
+	case "integer", "float", "number":
+		if i.Minimum != nil {
+			prop["minimum"] = *i.Minimum
+		}
+
+		if i.Maximum != nil {
+			prop["maximum"] = *i.Maximum
+		}
+
+	case "array":
+		if i.MinLength > 0 {
+			prop["minItems"] = i.MinLength
+		}
+
+		if i.MaxLength > 0 {
+			prop["maxItems"] = i.MaxLength
+		}
+
+		if i.Items != nil {
+			prop["items"] = i.Items.jsonSchema()
+		}
+
+	case "hash":
+		if len(i.Properties) > 0 {
+			properties := make(map[string]interface{})
+			required := []string{}
+
+			for name, field := range i.Properties {
+				properties[name] = field.jsonSchema()
+
+				if !field.Optional {
+					required = append(required, name)
+				}
+			}
+
+			sort.Strings(required)
+
+			prop["properties"] = properties
+			prop["required"] = required
+		}
+	}
+
+	if i.Default != nil {
+		prop["default"] = i.Default
+	}
+
+	return prop
+}
+
+// jsonSchemaType sets the JSON Schema type matching a DDL type, unknown and empty types allow any value
+func jsonSchemaType(prop map[string]interface{}, t string) {
+	switch strings.ToLower(t) {
+	case "string", "list":
+		prop["type"] = "string"
+	case "integer":
+		prop["type"] = "integer"
+	case "float", "number":
+		prop["type"] = "number"
+	case "boolean":
+		prop["type"] = "boolean"
+	case "hash":
+		prop["type"] = "object"
+	case "array":
+		prop["type"] = "array"
+	}
+}
+
+// jsonSchemaValidation maps the DDL string validators onto formats and patterns,
+// unknown named validators are not represented in the schema
+func jsonSchemaValidation(prop map[string]interface{}, validation string) {
+	switch validation {
+	case "":
+	case "shellsafe":
+		prop["pattern"] = shellsafePattern
+	case "ipv4address":
+		prop["format"] = "ipv4"
+	case "ipv6address":
+		prop["format"] = "ipv6"
+	case "ipaddress":
+		prop["anyOf"] = []map[string]string{{"format": "ipv4"}, {"format": "ipv6"}}
+	case "hostname", "fqdn":
+		prop["format"] = "hostname"
+	case "url":
+		prop["format"] = "uri"
+	case "email":
+		prop["format"] = "email"
+	case "semver":
+		prop["pattern"] = semverRe.String()
+	case "uuid":
+		prop["pattern"] = uuidRe.String()
+	default:
+		if !namedValidatorRe.MatchString(validation) {
+			prop["pattern"] = validation
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+var _ = Describe("McoRPC/DDL/Agent/JSONSchema", func() {
+	var ddl *DDL
+	var restart *Action
+
+	validate := func(schema []byte, doc string) bool {
+		s, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schema))
+		Expect(err).ToNot(HaveOccurred())
+
+		res, err := s.Validate(gojsonschema.NewStringLoader(doc))
+		Expect(err).ToNot(HaveOccurred())
+
+		return res.Valid()
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		ddl, err = New(filepath.Join("testdata", "ruby", "mcollective", "agent", "service.ddl"))
+		Expect(err).ToNot(HaveOccurred())
+
+		restart, err = ddl.ActionInterface("restart")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("InputSchema", func() {
+		It("Should describe the inputs", func() {
+			schema, err := restart.InputSchema()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(schema).To(MatchJSON(`{
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"title": "restart input",
+				"description": "Restart a service",
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["service"],
+				"properties": {
+					"service": {
+						"title": "Service Name",
+						"description": "The service to restart",
+						"type": "string",
+						"maxLength": 90,
+						"pattern": "^[^` + "`" + `$;|<>&]*(&[^` + "`" + `$;|<>&]+)*&?$"
+					},
+					"signal": {
+						"title": "Signal",
+						"description": "The signal to send",
+						"type": "string",
+						"enum": ["HUP", "TERM"],
+						"default": "TERM"
+					}
+				}
+			}`))
+		})
+
+		It("Should validate requests like the DDL", func() {
+			schema, err := restart.InputSchema()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(validate(schema, `{"service":"httpd"}`)).To(BeTrue())
+			Expect(validate(schema, `{"service":"httpd", "signal":"HUP"}`)).To(BeTrue())
+			Expect(validate(schema, `{"service":"a&b"}`)).To(BeTrue())
+			Expect(validate(schema, `{"signal":"HUP"}`)).To(BeFalse())
+			Expect(validate(schema, `{"service":"httpd", "signal":"KILL"}`)).To(BeFalse())
+			Expect(validate(schema, `{"service":"httpd; rm -rf /"}`)).To(BeFalse())
+			Expect(validate(schema, `{"service":"a&&b"}`)).To(BeFalse())
+			Expect(validate(schema, `{"service":"httpd", "other":1}`)).To(BeFalse())
+		})
+
+		It("Should support regular expressions and ip validators", func() {
+			status, err := ddl.ActionInterface("status")
+			Expect(err).ToNot(HaveOccurred())
+
+			status.Input["address"] = &ActionInputItem{Prompt: "Address", Type: "string", Validation: "ipaddress", Optional: true}
+
+			schema, err := status.InputSchema()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(validate(schema, `{"service":"sshd@1"}`)).To(BeTrue())
+			Expect(validate(schema, `{"service":"ssh d"}`)).To(BeFalse())
+			Expect(validate(schema, `{"service":"sshd", "address":"::1"}`)).To(BeTrue())
+			Expect(validate(schema, `{"service":"sshd", "address":"192.168.1.1"}`)).To(BeTrue())
+			Expect(validate(schema, `{"service":"sshd", "address":"example.net"}`)).To(BeFalse())
+		})
+
+		It("Should support ranges, array items and hash fields", func() {
+			min := 1.0
+			max := 65535.0
+
+			restart.Input["retries"] = &ActionInputItem{Type: "integer", Optional: true, Minimum: &min}
+			restart.Input["tags"] = &ActionInputItem{Type: "array", Optional: true, MaxLength: 2, Items: &ActionInputItem{Type: "string", MinLength: 2}}
+			restart.Input["proxy"] = &ActionInputItem{Type: "hash", Optional: true, Properties: map[string]*ActionInputItem{
+				"host": {Type: "string"},
+				"port": {Type: "integer", Optional: true, Maximum: &max},
+			}}
+
+			schema, err := restart.InputSchema()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(validate(schema, `{"service":"httpd", "retries": 1, "tags": ["ab"], "proxy": {"host": "proxy", "port": 3128}}`)).To(BeTrue())
+			Expect(validate(schema, `{"service":"httpd", "retries": 0}`)).To(BeFalse())
+			Expect(validate(schema, `{"service":"httpd", "tags": ["ab", "cd", "ef"]}`)).To(BeFalse())
+			Expect(validate(schema, `{"service":"httpd", "tags": ["a"]}`)).To(BeFalse())
+			Expect(validate(schema, `{"service":"httpd", "proxy": {"port": 3128}}`)).To(BeFalse())
+			Expect(validate(schema, `{"service":"httpd", "proxy": {"host": "proxy", "port": 70000}}`)).To(BeFalse())
+		})
+	})
+
+	Describe("OutputSchema", func() {
+		It("Should describe the outputs", func() {
+			schema, err := restart.OutputSchema()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(schema).To(MatchJSON(`{
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"title": "restart output",
+				"description": "Restart a service",
+				"type": "object",
+				"properties": {
+					"duration": {"title": "Duration", "description": "Time taken to restart", "type": "number"},
+					"status": {"title": "Service Status", "description": "The status of the service after restarting", "default": "unknown"}
+				}
+			}`))
+
+			Expect(validate(schema, `{"duration": 1.5, "status": "running"}`)).To(BeTrue())
+			Expect(validate(schema, `{"duration": "slow"}`)).To(BeFalse())
+		})
+	})
+
+	Describe("JSONSchema", func() {
+		It("Should bundle every action", func() {
+			bundle, err := ddl.JSONSchema()
+			Expect(err).ToNot(HaveOccurred())
+
+			schema := map[string]interface{}{}
+			Expect(json.Unmarshal(bundle, &schema)).ToNot(HaveOccurred())
+			Expect(schema["title"]).To(Equal("service agent"))
+			Expect(schema["definitions"]).To(HaveKey("status"))
+			Expect(schema["definitions"]).To(HaveKey("restart"))
+
+			schema["$ref"] = "#/definitions/restart/definitions/input"
+			ref, err := json.Marshal(schema)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(validate(ref, `{"service":"httpd"}`)).To(BeTrue())
+			Expect(validate(ref, `{"service":"httpd", "signal":"KILL"}`)).To(BeFalse())
+		})
+	})
+})
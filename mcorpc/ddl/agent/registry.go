@@ -0,0 +1,269 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Registry indexes the agent DDLs found in a set of libdirs and caches them once parsed
+//
+// When more than one file describes the same agent the one in the earliest libdir is used, within
+// a libdir JSON DDLs are preferred over Ruby ones, the files that were not used are available
+// using Shadowed(). Agents of different providers do not hide each other when looked up using
+// FindProvider() or ByProvider()
+type Registry struct {
+	libdirs []string
+	opts    []LoadOption
+
+	paths   map[string][]string
+	ddls    map[string]*DDL
+	indexed time.Time
+
+	sync.Mutex
+}
+
+var (
+	registries   = make(map[string]*Registry)
+	registriesMu = &sync.Mutex{}
+
+	// rescanInterval is how often the libdirs are indexed again when looking up unknown agents
+	rescanInterval = time.Minute
+)
+
+// NewRegistry creates a registry indexing the agents found in libdirs, DDLs are loaded using opts
+func NewRegistry(libdirs []string, opts ...LoadOption) *Registry {
+	r := &Registry{
+		libdirs: libdirs,
+		opts:    opts,
+	}
+
+	r.Refresh()
+
+	return r
+}
+
+// SharedRegistry is a process wide registry for the libdirs that is created on first use,
+// the libdirs and strict validation setting of opts identify the registry
+func SharedRegistry(libdirs []string, opts ...LoadOption) *Registry {
+	lopts := &loadOptions{}
+	for _, opt := range opts {
+		opt(lopts)
+	}
+
+	key := fmt.Sprintf("%s;%t", strings.Join(libdirs, string(os.PathListSeparator)), lopts.strictValidators)
+
+	registriesMu.Lock()
+	defer registriesMu.Unlock()
+
+	r, ok := registries[key]
+	if !ok {
+		r = NewRegistry(libdirs, opts...)
+		registries[key] = r
+	}
+
+	return r
+}
+
+// Refresh indexes the libdirs again and clears all cached DDLs and misses
+func (r *Registry) Refresh() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.index()
+	r.ddls = make(map[string]*DDL)
+}
+
+// index finds all DDL files in the libdirs, cached DDLs are kept as they are cached by path
+func (r *Registry) index() {
+	paths := make(map[string][]string)
+
+	EachFile(r.libdirs, func(name string, path string) bool {
+		paths[name] = append(paths[name], path)
+		return false
+	})
+
+	r.paths = paths
+	r.indexed = time.Now()
+}
+
+// candidates are the files describing an agent in order of precedence, misses are cached
+// but the libdirs are indexed again at most every rescanInterval so agents added after the
+// registry was created are found without walking the libdirs on every lookup
+func (r *Registry) candidates(agent string) []string {
+	paths, ok := r.paths[agent]
+	if !ok && time.Since(r.indexed) > rescanInterval {
+		r.index()
+		paths = r.paths[agent]
+	}
+
+	return paths
+}
+
+// Names is the sorted list of agents found in the libdirs
+func (r *Registry) Names() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	names := []string{}
+	for name := range r.paths {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Path is the path to the DDL file used for an agent
+func (r *Registry) Path(agent string) (string, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	paths := r.candidates(agent)
+	if len(paths) == 0 {
+		return "", false
+	}
+
+	return paths[0], true
+}
+
+// Shadowed is the list of DDL files for an agent that are not used because another file takes precedence
+func (r *Registry) Shadowed(agent string) []string {
+	r.Lock()
+	defer r.Unlock()
+
+	paths := r.candidates(agent)
+	if len(paths) < 2 {
+		return []string{}
+	}
+
+	return append([]string{}, paths[1:]...)
+}
+
+// Find loads the DDL for an agent, DDLs are cached until the next Refresh() while failures to
+// load them are retried on every call. Every call returns a new copy of the DDL so aggregate
+// state is not shared between callers
+func (r *Registry) Find(agent string) (*DDL, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	paths := r.candidates(agent)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("could not find DDL file for %s", agent)
+	}
+
+	ddl, err := r.load(agent, paths[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return ddl.clone()
+}
+
+// FindProvider loads the DDL for an agent from the first file that describes it using provider,
+// DDLs without a provider are considered to be ruby agents. The other files for the same provider
+// are returned as shadowed. A nil DDL and error means no file describes the agent using provider
+func (r *Registry) FindProvider(agent string, provider string) (ddl *DDL, shadowed []string, err error) {
+	r.Lock()
+	defer r.Unlock()
+
+	shadowed = []string{}
+
+	var loadErr error
+
+	for _, path := range r.candidates(agent) {
+		found, err := r.load(agent, path)
+		if err != nil {
+			if loadErr == nil {
+				loadErr = err
+			}
+
+			continue
+		}
+
+		if ddlProvider(found) != provider {
+			continue
+		}
+
+		if ddl != nil {
+			shadowed = append(shadowed, path)
+			continue
+		}
+
+		ddl = found
+	}
+
+	if ddl == nil {
+		return nil, shadowed, loadErr
+	}
+
+	ddl, err = ddl.clone()
+	if err != nil {
+		return nil, shadowed, err
+	}
+
+	return ddl, shadowed, nil
+}
+
+func (r *Registry) load(agent string, path string) (*DDL, error) {
+	if ddl, ok := r.ddls[path]; ok {
+		return ddl, nil
+	}
+
+	ddl, err := New(path, r.opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not load agent %s: %s", agent, err)
+	}
+
+	r.ddls[path] = ddl
+
+	return ddl, nil
+}
+
+// ByProvider loads all DDLs for agents with a specific provider, DDLs without a provider
+// are considered to be ruby agents. DDLs that fail to load are skipped, use FindProvider()
+// to retrieve their errors
+func (r *Registry) ByProvider(provider string) []*DDL {
+	ddls := []*DDL{}
+
+	for _, name := range r.Names() {
+		ddl, _, err := r.FindProvider(name, provider)
+		if err != nil || ddl == nil {
+			continue
+		}
+
+		ddls = append(ddls, ddl)
+	}
+
+	return ddls
+}
+
+func ddlProvider(ddl *DDL) string {
+	if ddl.Metadata.Provider == "" {
+		return "ruby"
+	}
+
+	return ddl.Metadata.Provider
+}
+
+func (d *DDL) clone() (*DDL, error) {
+	j, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	ddl := &DDL{}
+	err = json.Unmarshal(j, ddl)
+	if err != nil {
+		return nil, err
+	}
+
+	ddl.SourceLocation = d.SourceLocation
+
+	return ddl, nil
+}
@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("McoRPC/DDL/Agent/Registry", func() {
+	var registry *Registry
+	var libdirs []string
+
+	BeforeEach(func() {
+		libdirs = []string{filepath.Join("testdata", "ruby"), filepath.Join("testdata", "registry"), "testdata"}
+		registry = NewRegistry(libdirs)
+	})
+
+	It("Should index all agents", func() {
+		Expect(registry.Names()).To(Equal([]string{"broken", "echo", "package", "service"}))
+
+		path, ok := registry.Path("service")
+		Expect(ok).To(BeTrue())
+		Expect(path).To(Equal(filepath.Join("testdata", "ruby", "mcollective", "agent", "service.ddl")))
+
+		_, ok = registry.Path("unknown")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("Should prefer agents from earlier libdirs", func() {
+		path, _ := registry.Path("echo")
+		Expect(path).To(Equal(filepath.Join("testdata", "ruby", "mcollective", "agent", "echo.json")))
+		Expect(registry.Shadowed("echo")).To(Equal([]string{filepath.Join("testdata", "registry", "mcollective", "agent", "echo.json")}))
+
+		echo, err := registry.Find("echo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(echo.Metadata.Version).To(Equal("1.0.0"))
+
+		echo, err = NewRegistry([]string{filepath.Join("testdata", "registry"), filepath.Join("testdata", "ruby")}).Find("echo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(echo.Metadata.Version).To(Equal("2.0.0"))
+	})
+
+	It("Should cache DDLs but return copies", func() {
+		pkg, err := registry.Find("package")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pkg.SourceLocation).To(Equal(filepath.Join("testdata", "mcollective", "agent", "package.json")))
+
+		pkg.Metadata.Version = "9.9.9"
+
+		again, err := registry.Find("package")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(again.Metadata.Version).To(Equal("5.0.0"))
+		Expect(registry.ddls[pkg.SourceLocation]).ToNot(BeIdenticalTo(again))
+	})
+
+	It("Should report load failures", func() {
+		_, err := registry.Find("broken")
+		Expect(err).To(MatchError(HavePrefix("could not load agent broken: ")))
+
+		_, err = registry.Find("unknown")
+		Expect(err).To(MatchError("could not find DDL file for unknown"))
+	})
+
+	It("Should retry failed loads and find new agents", func() {
+		dir, err := ioutil.TempDir("", "registry")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		agents := filepath.Join(dir, "mcollective", "agent")
+		Expect(os.MkdirAll(agents, 0700)).ToNot(HaveOccurred())
+
+		registry = NewRegistry([]string{dir})
+
+		_, err = registry.Find("package")
+		Expect(err).To(MatchError("could not find DDL file for package"))
+
+		Expect(ioutil.WriteFile(filepath.Join(agents, "package.json"), []byte("{"), 0600)).ToNot(HaveOccurred())
+
+		// misses are cached until the rescan interval passed
+		_, err = registry.Find("package")
+		Expect(err).To(MatchError("could not find DDL file for package"))
+
+		registry.indexed = time.Now().Add(-2 * rescanInterval)
+		_, err = registry.Find("package")
+		Expect(err).To(MatchError(HavePrefix("could not load agent package: ")))
+
+		dat, err := ioutil.ReadFile(filepath.Join("testdata", "mcollective", "agent", "package.json"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(agents, "package.json"), dat, 0600)).ToNot(HaveOccurred())
+
+		pkg, err := registry.Find("package")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pkg.Metadata.Name).To(Equal("package"))
+
+		indexed := registry.indexed
+		_, err = registry.Find("unknown")
+		Expect(err).To(MatchError("could not find DDL file for unknown"))
+		Expect(registry.indexed).To(Equal(indexed))
+	})
+
+	It("Should find agents per provider", func() {
+		echo, shadowed, err := registry.FindProvider("echo", "external")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(shadowed).To(BeEmpty())
+		Expect(echo.Metadata.Version).To(Equal("2.0.0"))
+
+		echo, shadowed, err = registry.FindProvider("echo", "ruby")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(shadowed).To(BeEmpty())
+		Expect(echo.Metadata.Version).To(Equal("1.0.0"))
+
+		pkg, _, err := registry.FindProvider("package", "external")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pkg).To(BeNil())
+
+		_, _, err = registry.FindProvider("broken", "external")
+		Expect(err).To(MatchError(HavePrefix("could not load agent broken: ")))
+	})
+
+	It("Should look up agents by provider", func() {
+		names := func(ddls []*DDL) []string {
+			res := []string{}
+			for _, d := range ddls {
+				res = append(res, d.Metadata.Name)
+			}
+			return res
+		}
+
+		Expect(names(registry.ByProvider("ruby"))).To(Equal([]string{"echo", "package", "service"}))
+		Expect(names(registry.ByProvider("external"))).To(Equal([]string{"echo"}))
+		Expect(names(NewRegistry([]string{filepath.Join("testdata", "registry")}).ByProvider("external"))).To(Equal([]string{"echo"}))
+	})
+
+	It("Should support refreshing the index", func() {
+		_, err := registry.Find("package")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(registry.ddls).To(HaveKey(filepath.Join("testdata", "mcollective", "agent", "package.json")))
+
+		registry.Refresh()
+		Expect(registry.ddls).To(BeEmpty())
+		Expect(registry.Names()).To(HaveLen(4))
+	})
+
+	It("Should find agents using the shared registry", func() {
+		pkg, err := Find("package", libdirs)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pkg.Metadata.Name).To(Equal("package"))
+
+		_, ok := SharedRegistry(libdirs).ddls[pkg.SourceLocation]
+		Expect(ok).To(BeTrue())
+	})
+
+	It("Should share registries", func() {
+		Expect(SharedRegistry(libdirs)).To(BeIdenticalTo(SharedRegistry(libdirs)))
+		Expect(SharedRegistry(libdirs)).ToNot(BeIdenticalTo(SharedRegistry(libdirs, StrictValidators(true))))
+		Expect(SharedRegistry(libdirs)).ToNot(BeIdenticalTo(SharedRegistry(libdirs[1:])))
+	})
+})
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	config "github.com/choria-io/go-config"
+)
+
+const (
+	// CoerceRepliesOption is the configuration option that enables ReplyValidation.Coerce
+	CoerceRepliesOption = "plugin.choria.ddl.coerce_replies"
+
+	// StrictRepliesOption is the configuration option that enables ReplyValidation.Strict
+	StrictRepliesOption = "plugin.choria.ddl.strict_replies"
+)
+
+// ReplyValidation configures how replies are checked against the outputs declared in the DDL
+type ReplyValidation struct {
+	// Coerce converts values like "5" into the declared output type where possible
+	Coerce bool
+
+	// Strict marks replies that do not match the DDL as failed using the UnknownError status
+	Strict bool
+}
+
+// ReplyValidationFromConfig creates a ReplyValidation based on the CoerceRepliesOption and StrictRepliesOption settings
+func ReplyValidationFromConfig(cfg *config.Config) ReplyValidation {
+	return ReplyValidation{
+		Coerce: configBool(cfg, CoerceRepliesOption),
+		Strict: configBool(cfg, StrictRepliesOption),
+	}
+}
+
+// ValidateReplyJSON validates reply data in JSON format against the DDL, see ValidateReplyData
+func (a *Action) ValidateReplyJSON(reply json.RawMessage, coerce bool) (result map[string]interface{}, warnings []string, err error) {
+	result = make(map[string]interface{})
+
+	if len(reply) > 0 && string(reply) != "null" {
+		err = json.Unmarshal(reply, &result)
+		if err != nil {
+			return result, []string{}, fmt.Errorf("reply data is not a hash: %s", err)
+		}
+	}
+
+	warnings, err = a.ValidateReplyData(result, coerce)
+
+	return result, warnings, err
+}
+
+// ValidateReplyData checks that the values in reply data match the types of the declared outputs, outputs
+// that are not declared in the DDL produce warnings. When coerce is true values are converted to the
+// declared type in place where possible, for example "5" becomes 5 for integer outputs
+func (a *Action) ValidateReplyData(data map[string]interface{}, coerce bool) (warnings []string, err error) {
+	warnings = []string{}
+	errs := []string{}
+
+	names := []string{}
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		val := data[name]
+
+		output, ok := a.Output[name]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("reply contains an output '%s' that is not declared in the DDL", name))
+			continue
+		}
+
+		if outputTypeMatches(output.Type, val) {
+			continue
+		}
+
+		if coerce {
+			converted, err := coerceOutput(output.Type, val)
+			if err == nil {
+				data[name] = converted
+				warnings = append(warnings, fmt.Sprintf("output '%s' was converted to %s", name, strings.ToLower(output.Type)))
+				continue
+			}
+		}
+
+		errs = append(errs, fmt.Sprintf("output '%s' should be of type %s but got %T", name, strings.ToLower(output.Type), val))
+	}
+
+	if len(errs) > 0 {
+		return warnings, fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+
+	return warnings, nil
+}
+
+// outputTypeMatches checks a value against an output type, outputs without a type and nil values always match
+func outputTypeMatches(t string, val interface{}) bool {
+	if val == nil {
+		return true
+	}
+
+	switch strings.ToLower(t) {
+	case "string", "list":
+		return isString(val)
+	case "integer":
+		return isAnyInt(val) || isWholeFloat(val)
+	case "float", "number":
+		return isNumber(val)
+	case "boolean":
+		return isBool(val)
+	case "hash":
+		return isHash(val)
+	case "array":
+		return isArray(val)
+	default:
+		return true
+	}
+}
+
+func coerceOutput(t string, val interface{}) (interface{}, error) {
+	switch strings.ToLower(t) {
+	case "string", "list":
+		if isHash(val) || isArray(val) {
+			return nil, fmt.Errorf("cannot convert %T to a string", val)
+		}
+
+		return fmt.Sprintf("%v", val), nil
+	}
+
+	sval, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to %s", val, t)
+	}
+
+	return ValToDDLType(t, strings.TrimSpace(sval))
+}
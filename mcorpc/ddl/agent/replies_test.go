@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"encoding/json"
+
+	config "github.com/choria-io/go-config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("McoRPC/DDL/Agent/Replies", func() {
+	var act *Action
+
+	BeforeEach(func() {
+		act = &Action{
+			Name: "test",
+			Output: map[string]*ActionOutputItem{
+				"count":   {Type: "integer"},
+				"load":    {Type: "float"},
+				"name":    {Type: "string"},
+				"enabled": {Type: "boolean"},
+				"labels":  {Type: "hash"},
+				"members": {Type: "array"},
+				"other":   {},
+			},
+		}
+	})
+
+	Describe("ValidateReplyData", func() {
+		It("Should accept valid replies", func() {
+			warnings, err := act.ValidateReplyData(map[string]interface{}{
+				"count":   float64(5),
+				"load":    1,
+				"name":    "x",
+				"enabled": true,
+				"labels":  map[string]interface{}{"a": "b"},
+				"members": []interface{}{"a"},
+				"other":   []string{},
+			}, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("Should detect invalid types", func() {
+			_, err := act.ValidateReplyData(map[string]interface{}{"count": "5", "enabled": "yes", "load": nil}, false)
+			Expect(err).To(MatchError("output 'count' should be of type integer but got string, output 'enabled' should be of type boolean but got string"))
+
+			_, err = act.ValidateReplyData(map[string]interface{}{"count": 1.5}, false)
+			Expect(err).To(MatchError("output 'count' should be of type integer but got float64"))
+		})
+
+		It("Should flag undeclared outputs", func() {
+			warnings, err := act.ValidateReplyData(map[string]interface{}{"name": "x", "extra": 1}, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(warnings).To(Equal([]string{"reply contains an output 'extra' that is not declared in the DDL"}))
+		})
+
+		It("Should coerce values when requested", func() {
+			data := map[string]interface{}{"count": "5", "load": "1.5", "enabled": "true", "name": 10, "members": `["a"]`}
+			warnings, err := act.ValidateReplyData(data, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(warnings).To(HaveLen(5))
+			Expect(data).To(Equal(map[string]interface{}{"count": int64(5), "load": 1.5, "enabled": true, "name": "10", "members": []interface{}{"a"}}))
+
+			_, err = act.ValidateReplyData(map[string]interface{}{"count": "five"}, true)
+			Expect(err).To(MatchError("output 'count' should be of type integer but got string"))
+		})
+	})
+
+	Describe("ValidateReplyJSON", func() {
+		It("Should validate JSON replies", func() {
+			result, _, err := act.ValidateReplyJSON(json.RawMessage(`{"count": "5"}`), true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(map[string]interface{}{"count": int64(5)}))
+
+			_, _, err = act.ValidateReplyJSON(json.RawMessage(`[1]`), false)
+			Expect(err).To(HaveOccurred())
+
+			result, _, err = act.ValidateReplyJSON(nil, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(BeEmpty())
+		})
+	})
+
+	Describe("ReplyValidationFromConfig", func() {
+		It("Should read the configuration", func() {
+			cfg, err := config.NewDefaultConfig()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ReplyValidationFromConfig(cfg)).To(Equal(ReplyValidation{}))
+
+			cfg.SetOption(CoerceRepliesOption, "yes")
+			cfg.SetOption(StrictRepliesOption, "true")
+			Expect(ReplyValidationFromConfig(cfg)).To(Equal(ReplyValidation{Coerce: true, Strict: true}))
+			Expect(ReplyValidationFromConfig(nil)).To(Equal(ReplyValidation{}))
+		})
+	})
+})
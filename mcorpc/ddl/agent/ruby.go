@@ -0,0 +1,932 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/choria-io/go-choria/server/agents"
+)
+
+// NewFromRuby creates a new DDL from a legacy MCollective Ruby DDL file
+func NewFromRuby(file string, opts ...LoadOption) (*DDL, error) {
+	dat, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not load DDL data: %s", err)
+	}
+
+	ddl, err := ParseRuby(dat)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse Ruby DDL %s: %s", file, err)
+	}
+
+	ddl.SourceLocation = file
+
+	j, err := ddl.ToJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ValidateJSON(j)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DDL %s: %s", file, err)
+	}
+
+	err = ddl.checkValidators(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DDL %s: %s", file, err)
+	}
+
+	return ddl, nil
+}
+
+// ParseRuby parses the metadata, action, input, output, display and summarize
+// statements of a MCollective Ruby DDL, unsupported Ruby constructs result in an error
+func ParseRuby(dat []byte) (*DDL, error) {
+	p := &rubyParser{lex: newRubyLexer(string(dat))}
+
+	stmts, err := p.statements(false)
+	if err != nil {
+		return nil, err
+	}
+
+	ddl := &DDL{
+		Schema:  SchemaURL,
+		Actions: []*Action{},
+	}
+
+	for _, stmt := range stmts {
+		switch stmt.name {
+		case "metadata":
+			ddl.Metadata, err = rubyMetadata(stmt)
+			if err != nil {
+				return nil, err
+			}
+
+		case "requires":
+			// mcollective version requirements do not apply to choria
+
+		case "action":
+			action, err := rubyAction(stmt)
+			if err != nil {
+				return nil, err
+			}
+
+			ddl.Actions = append(ddl.Actions, action)
+
+		default:
+			return nil, fmt.Errorf("unsupported statement '%s' on line %d", stmt.name, stmt.line)
+		}
+	}
+
+	if ddl.Metadata == nil {
+		return nil, fmt.Errorf("no metadata found")
+	}
+
+	ddl.normalize()
+
+	return ddl, nil
+}
+
+// ToJSON renders the DDL in the JSON DDL format
+func (d *DDL) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+func rubyMetadata(stmt *rbStatement) (*agents.Metadata, error) {
+	md := &agents.Metadata{}
+
+	for k, v := range stmt.kwargs {
+		var err error
+
+		switch k {
+		case "name":
+			md.Name, err = rubyString(v)
+		case "description":
+			md.Description, err = rubyString(v)
+		case "author":
+			md.Author, err = rubyString(v)
+		case "license":
+			md.License, err = rubyString(v)
+		case "version":
+			md.Version, err = rubyString(v)
+		case "url":
+			md.URL, err = rubyString(v)
+		case "provider":
+			md.Provider, err = rubyString(v)
+		case "timeout":
+			md.Timeout, err = rubyInt(v)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata %s on line %d: %s", k, stmt.line, err)
+		}
+	}
+
+	return md, nil
+}
+
+func rubyAction(stmt *rbStatement) (*Action, error) {
+	if len(stmt.args) != 1 {
+		return nil, fmt.Errorf("action on line %d requires a name", stmt.line)
+	}
+
+	name, err := rubyString(stmt.args[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid action name on line %d: %s", stmt.line, err)
+	}
+
+	action := &Action{
+		Name:   name,
+		Input:  make(map[string]*ActionInputItem),
+		Output: make(map[string]*ActionOutputItem),
+	}
+
+	descr, ok := stmt.kwargs["description"]
+	if ok {
+		action.Description, err = rubyString(descr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid description for action %s on line %d: %s", name, stmt.line, err)
+		}
+	}
+
+	for _, s := range stmt.block {
+		switch s.name {
+		case "display":
+			if len(s.args) != 1 {
+				return nil, fmt.Errorf("display on line %d requires an argument", s.line)
+			}
+
+			action.Display, err = rubyString(s.args[0])
+
+		case "input":
+			var iname string
+			var input *ActionInputItem
+
+			iname, input, err = rubyInput(s)
+			if err == nil {
+				action.Input[iname] = input
+			}
+
+		case "output":
+			var oname string
+			var output *ActionOutputItem
+
+			oname, output, err = rubyOutput(s)
+			if err == nil {
+				action.Output[oname] = output
+			}
+
+		case "summarize":
+			for _, agg := range s.block {
+				var item ActionAggregateItem
+
+				item, err = rubyAggregate(agg)
+				if err != nil {
+					break
+				}
+
+				action.Aggregation = append(action.Aggregation, item)
+			}
+
+		default:
+			err = fmt.Errorf("unsupported statement '%s' on line %d", s.name, s.line)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid action %s: %s", name, err)
+		}
+	}
+
+	return action, nil
+}
+
+func rubyInput(stmt *rbStatement) (string, *ActionInputItem, error) {
+	if len(stmt.args) != 1 {
+		return "", nil, fmt.Errorf("input on line %d requires a name", stmt.line)
+	}
+
+	name, err := rubyString(stmt.args[0])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid input name on line %d: %s", stmt.line, err)
+	}
+
+	input := &ActionInputItem{}
+
+	for k, v := range stmt.kwargs {
+		switch k {
+		case "prompt":
+			input.Prompt, err = rubyString(v)
+		case "description":
+			input.Description, err = rubyString(v)
+		case "type":
+			input.Type, err = rubyString(v)
+		case "optional":
+			input.Optional, err = rubyBool(v)
+		case "maxlength":
+			input.MaxLength, err = rubyInt(v)
+		case "default":
+			input.Default = rubyPlain(v)
+		case "list":
+			input.Enum, err = rubyStrings(v)
+		case "minlength":
+			input.MinLength, err = rubyInt(v)
+		case "minimum":
+			input.Minimum, err = rubyFloat(v)
+		case "maximum":
+			input.Maximum, err = rubyFloat(v)
+		case "items":
+			err = rubyDecode(v, &input.Items)
+		case "properties":
+			err = rubyDecode(v, &input.Properties)
+		case "validation":
+			switch val := v.(type) {
+			case rbSymbol:
+				input.Validation = string(val)
+			case rbRegex:
+				input.Validation = string(val)
+			default:
+				input.Validation, err = rubyString(v)
+			}
+		}
+
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid %s for input %s on line %d: %s", k, name, stmt.line, err)
+		}
+	}
+
+	return name, input, nil
+}
+
+func rubyOutput(stmt *rbStatement) (string, *ActionOutputItem, error) {
+	if len(stmt.args) != 1 {
+		return "", nil, fmt.Errorf("output on line %d requires a name", stmt.line)
+	}
+
+	name, err := rubyString(stmt.args[0])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid output name on line %d: %s", stmt.line, err)
+	}
+
+	output := &ActionOutputItem{}
+
+	for k, v := range stmt.kwargs {
+		switch k {
+		case "description":
+			output.Description, err = rubyString(v)
+		case "display_as":
+			output.DisplayAs, err = rubyString(v)
+		case "type":
+			output.Type, err = rubyString(v)
+		case "default":
+			output.Default = rubyPlain(v)
+		}
+
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid %s for output %s on line %d: %s", k, name, stmt.line, err)
+		}
+	}
+
+	return name, output, nil
+}
+
+func rubyAggregate(stmt *rbStatement) (ActionAggregateItem, error) {
+	item := ActionAggregateItem{}
+
+	if stmt.name != "aggregate" {
+		return item, fmt.Errorf("unsupported statement '%s' in summarize block on line %d", stmt.name, stmt.line)
+	}
+
+	if len(stmt.args) != 1 {
+		return item, fmt.Errorf("aggregate on line %d requires a function", stmt.line)
+	}
+
+	call, ok := stmt.args[0].(*rbCall)
+	if !ok || len(call.args) < 1 {
+		return item, fmt.Errorf("aggregate on line %d should be in the form function(:output)", stmt.line)
+	}
+
+	output, err := rubyString(call.args[0])
+	if err != nil {
+		return item, fmt.Errorf("invalid aggregate output on line %d: %s", stmt.line, err)
+	}
+
+	args := []interface{}{output}
+	if len(call.kwargs) > 0 {
+		args = append(args, rubyPlain(call.kwargs))
+	}
+
+	item.Function = call.name
+	item.Arguments, err = json.Marshal(args)
+	if err != nil {
+		return item, fmt.Errorf("invalid aggregate on line %d: %s", stmt.line, err)
+	}
+
+	return item, nil
+}
+
+type rbSymbol string
+type rbRegex string
+
+type rbCall struct {
+	name   string
+	args   []interface{}
+	kwargs map[string]interface{}
+}
+
+type rbStatement struct {
+	name   string
+	line   int
+	args   []interface{}
+	kwargs map[string]interface{}
+	block  []*rbStatement
+}
+
+// rubyPlain converts parsed ruby values to the types found in JSON documents
+func rubyPlain(v interface{}) interface{} {
+	switch val := v.(type) {
+	case rbSymbol:
+		return string(val)
+
+	case rbRegex:
+		return string(val)
+
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, item := range val {
+			res[i] = rubyPlain(item)
+		}
+
+		return res
+
+	case map[string]interface{}:
+		res := make(map[string]interface{})
+		for k, item := range val {
+			res[k] = rubyPlain(item)
+		}
+
+		return res
+
+	default:
+		return val
+	}
+}
+
+func rubyString(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case rbSymbol:
+		return string(val), nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("expected a string but got %v", v)
+	}
+}
+
+func rubyStrings(v interface{}) ([]string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list but got %v", v)
+	}
+
+	res := []string{}
+	for _, item := range list {
+		s, err := rubyString(item)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, s)
+	}
+
+	return res, nil
+}
+
+func rubyInt(v interface{}) (int, error) {
+	f, ok := v.(float64)
+	if !ok {
+		return 0, fmt.Errorf("expected a number but got %v", v)
+	}
+
+	return int(f), nil
+}
+
+func rubyFloat(v interface{}) (*float64, error) {
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("expected a number but got %v", v)
+	}
+
+	return &f, nil
+}
+
+// rubyDecode decodes hashes like input item definitions into target using their JSON form
+func rubyDecode(v interface{}, target interface{}) error {
+	if _, ok := v.(map[string]interface{}); !ok {
+		return fmt.Errorf("expected a hash but got %v", v)
+	}
+
+	j, err := json.Marshal(rubyPlain(v))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(j, target)
+}
+
+func rubyBool(v interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a boolean but got %v", v)
+	}
+
+	return b, nil
+}
+
+type rubyTokenKind int
+
+const (
+	rtEOF rubyTokenKind = iota
+	rtNewline
+	rtIdent
+	rtLabel
+	rtSymbol
+	rtString
+	rtNumber
+	rtRegex
+	rtArrow
+	rtComma
+	rtLParen
+	rtRParen
+	rtLBracket
+	rtRBracket
+	rtLBrace
+	rtRBrace
+)
+
+type rubyToken struct {
+	kind  rubyTokenKind
+	value string
+	line  int
+}
+
+type rubyLexer struct {
+	src    []rune
+	pos    int
+	line   int
+	depth  int
+	last   rubyTokenKind
+	peeked *rubyToken
+}
+
+func newRubyLexer(src string) *rubyLexer {
+	return &rubyLexer{src: []rune(src), line: 1, last: rtNewline}
+}
+
+func (l *rubyLexer) peek() (*rubyToken, error) {
+	if l.peeked != nil {
+		return l.peeked, nil
+	}
+
+	t, err := l.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	l.peeked = t
+
+	return t, nil
+}
+
+func (l *rubyLexer) next() (*rubyToken, error) {
+	t, err := l.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	l.peeked = nil
+	l.last = t.kind
+
+	return t, nil
+}
+
+func (l *rubyLexer) scan() (*rubyToken, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+
+		switch {
+		case c == '\n':
+			l.pos++
+			l.line++
+
+			// statements continue over new lines inside brackets and after commas and =>
+			if l.depth > 0 || l.last == rtComma || l.last == rtArrow || l.last == rtNewline {
+				continue
+			}
+
+			return &rubyToken{kind: rtNewline, line: l.line - 1}, nil
+
+		case c == '\\' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '\n':
+			l.pos += 2
+			l.line++
+
+		case unicode.IsSpace(c):
+			l.pos++
+
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+
+		default:
+			return l.token()
+		}
+	}
+
+	return &rubyToken{kind: rtEOF, line: l.line}, nil
+}
+
+func (l *rubyLexer) token() (*rubyToken, error) {
+	c := l.src[l.pos]
+	line := l.line
+
+	simple := map[rune]rubyTokenKind{',': rtComma, '(': rtLParen, ')': rtRParen, '[': rtLBracket, ']': rtRBracket, '{': rtLBrace, '}': rtRBrace}
+
+	if kind, ok := simple[c]; ok {
+		l.pos++
+
+		switch kind {
+		case rtLParen, rtLBracket, rtLBrace:
+			l.depth++
+		case rtRParen, rtRBracket, rtRBrace:
+			l.depth--
+		}
+
+		return &rubyToken{kind: kind, value: string(c), line: line}, nil
+	}
+
+	switch {
+	case c == '=' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '>':
+		l.pos += 2
+		return &rubyToken{kind: rtArrow, value: "=>", line: line}, nil
+
+	case c == '"' || c == '\'':
+		s, err := l.quoted(c)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rubyToken{kind: rtString, value: s, line: line}, nil
+
+	case c == '/':
+		s, err := l.quoted('/')
+		if err != nil {
+			return nil, err
+		}
+
+		return &rubyToken{kind: rtRegex, value: s, line: line}, nil
+
+	case c == ':' && l.pos+1 < len(l.src) && (l.src[l.pos+1] == '"' || l.src[l.pos+1] == '\''):
+		l.pos++
+		s, err := l.quoted(l.src[l.pos])
+		if err != nil {
+			return nil, err
+		}
+
+		return &rubyToken{kind: rtSymbol, value: s, line: line}, nil
+
+	case c == ':' && l.pos+1 < len(l.src) && isRubyIdentStart(l.src[l.pos+1]):
+		l.pos++
+		return &rubyToken{kind: rtSymbol, value: l.identifier(), line: line}, nil
+
+	case c == '-' || unicode.IsDigit(c):
+		start := l.pos
+		l.pos++
+		for l.pos < len(l.src) && (unicode.IsDigit(l.src[l.pos]) || l.src[l.pos] == '.' || l.src[l.pos] == '_') {
+			l.pos++
+		}
+
+		return &rubyToken{kind: rtNumber, value: strings.Replace(string(l.src[start:l.pos]), "_", "", -1), line: line}, nil
+
+	case isRubyIdentStart(c):
+		ident := l.identifier()
+
+		// ruby 1.9 style hash keys like description: "foo"
+		if l.pos < len(l.src) && l.src[l.pos] == ':' && (l.pos+1 >= len(l.src) || l.src[l.pos+1] != ':') {
+			l.pos++
+			return &rubyToken{kind: rtLabel, value: ident, line: line}, nil
+		}
+
+		return &rubyToken{kind: rtIdent, value: ident, line: line}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected character '%c' on line %d", c, line)
+}
+
+func (l *rubyLexer) identifier() string {
+	start := l.pos
+	for l.pos < len(l.src) && (isRubyIdentStart(l.src[l.pos]) || unicode.IsDigit(l.src[l.pos])) {
+		l.pos++
+	}
+
+	if l.pos < len(l.src) && (l.src[l.pos] == '?' || l.src[l.pos] == '!') {
+		l.pos++
+	}
+
+	return string(l.src[start:l.pos])
+}
+
+// quoted reads a string delimited by q, double quoted strings and regular expressions support
+// the common escape sequences while single quoted strings only support escaping quotes and slashes
+func (l *rubyLexer) quoted(q rune) (string, error) {
+	line := l.line
+	var out strings.Builder
+
+	l.pos++
+
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		l.pos++
+
+		switch {
+		case c == q:
+			return out.String(), nil
+
+		case c == '\n':
+			l.line++
+			out.WriteRune(c)
+
+		case c == '\\' && l.pos < len(l.src):
+			n := l.src[l.pos]
+			l.pos++
+
+			switch {
+			case n == q || n == '\\' && q != '/':
+				out.WriteRune(n)
+			case q == '\'':
+				out.WriteRune('\\')
+				out.WriteRune(n)
+			case q == '/':
+				// regular expressions keep their escapes
+				if n != '/' {
+					out.WriteRune('\\')
+				}
+				out.WriteRune(n)
+			case n == 'n':
+				out.WriteRune('\n')
+			case n == 't':
+				out.WriteRune('\t')
+			default:
+				out.WriteRune(n)
+			}
+
+		default:
+			out.WriteRune(c)
+		}
+	}
+
+	return "", fmt.Errorf("unterminated string starting on line %d", line)
+}
+
+func isRubyIdentStart(c rune) bool {
+	return c == '_' || unicode.IsLetter(c)
+}
+
+type rubyParser struct {
+	lex *rubyLexer
+}
+
+// statements parses statements until EOF or, when inBlock is set, the end of a do block
+func (p *rubyParser) statements(inBlock bool) ([]*rbStatement, error) {
+	stmts := []*rbStatement{}
+
+	for {
+		t, err := p.lex.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case t.kind == rtNewline:
+			continue
+
+		case t.kind == rtEOF:
+			if inBlock {
+				return nil, fmt.Errorf("unexpected end of file, expected 'end'")
+			}
+
+			return stmts, nil
+
+		case t.kind == rtIdent && t.value == "end":
+			if !inBlock {
+				return nil, fmt.Errorf("unexpected 'end' on line %d", t.line)
+			}
+
+			return stmts, nil
+
+		case t.kind == rtIdent:
+			stmt, err := p.statement(t)
+			if err != nil {
+				return nil, err
+			}
+
+			stmts = append(stmts, stmt)
+
+		default:
+			return nil, fmt.Errorf("unsupported Ruby construct '%s' on line %d", t.value, t.line)
+		}
+	}
+}
+
+func (p *rubyParser) statement(name *rubyToken) (*rbStatement, error) {
+	stmt := &rbStatement{name: name.value, line: name.line, kwargs: make(map[string]interface{})}
+
+	t, err := p.lex.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	closer := rtNewline
+	if t.kind == rtLParen {
+		p.lex.next()
+		closer = rtRParen
+	}
+
+	stmt.args, stmt.kwargs, err = p.arguments(closer)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err = p.lex.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.kind == rtIdent && t.value == "do" {
+		p.lex.next()
+
+		stmt.block, err = p.statements(true)
+		if err != nil {
+			return nil, err
+		}
+
+		return stmt, nil
+	}
+
+	if closer == rtRParen || t.kind == rtNewline {
+		return stmt, nil
+	}
+
+	if t.kind != rtEOF {
+		return nil, fmt.Errorf("unexpected '%s' on line %d", t.value, t.line)
+	}
+
+	return stmt, nil
+}
+
+// arguments parses a comma separated list of values and key => value pairs until closer
+// is found, when closer is a new line the arguments also end at a do block or the end of file
+func (p *rubyParser) arguments(closer rubyTokenKind) ([]interface{}, map[string]interface{}, error) {
+	args := []interface{}{}
+	kwargs := make(map[string]interface{})
+
+	for {
+		t, err := p.lex.peek()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if t.kind == closer {
+			if closer != rtNewline {
+				p.lex.next()
+			}
+
+			return args, kwargs, nil
+		}
+
+		if closer == rtNewline && (t.kind == rtEOF || t.kind == rtIdent && t.value == "do") {
+			return args, kwargs, nil
+		}
+
+		if t.kind == rtLabel {
+			p.lex.next()
+
+			kwargs[t.value], err = p.value()
+			if err != nil {
+				return nil, nil, err
+			}
+		} else {
+			v, err := p.value()
+			if err != nil {
+				return nil, nil, err
+			}
+
+			a, err := p.lex.peek()
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if a.kind == rtArrow {
+				p.lex.next()
+
+				key, err := rubyString(v)
+				if err != nil {
+					return nil, nil, fmt.Errorf("invalid hash key on line %d: %s", a.line, err)
+				}
+
+				kwargs[key], err = p.value()
+				if err != nil {
+					return nil, nil, err
+				}
+			} else {
+				args = append(args, v)
+			}
+		}
+
+		t, err = p.lex.peek()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if t.kind == rtComma {
+			p.lex.next()
+		}
+	}
+}
+
+func (p *rubyParser) value() (interface{}, error) {
+	t, err := p.lex.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.kind {
+	case rtString:
+		return t.value, nil
+
+	case rtSymbol:
+		return rbSymbol(t.value), nil
+
+	case rtRegex:
+		return rbRegex(t.value), nil
+
+	case rtNumber:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' on line %d", t.value, t.line)
+		}
+
+		return f, nil
+
+	case rtLBracket:
+		args, kwargs, err := p.arguments(rtRBracket)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(kwargs) > 0 {
+			args = append(args, kwargs)
+		}
+
+		return args, nil
+
+	case rtLBrace:
+		_, kwargs, err := p.arguments(rtRBrace)
+		return kwargs, err
+
+	case rtIdent:
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "nil":
+			return nil, nil
+		}
+
+		n, err := p.lex.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		if n.kind != rtLParen {
+			return nil, fmt.Errorf("unsupported Ruby expression '%s' on line %d", t.value, t.line)
+		}
+
+		p.lex.next()
+
+		args, kwargs, err := p.arguments(rtRParen)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rbCall{name: t.value, args: args, kwargs: kwargs}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected '%s' on line %d", t.value, t.line)
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("McoRPC/DDL/Agent/Ruby", func() {
+	Describe("NewFromRuby", func() {
+		It("Should load Ruby DDL files", func() {
+			ddl, err := New(filepath.Join("testdata", "ruby", "mcollective", "agent", "service.ddl"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ddl.Schema).To(Equal(SchemaURL))
+			Expect(ddl.Metadata.Name).To(Equal("service"))
+			Expect(ddl.Metadata.Version).To(Equal("4.0.1"))
+			Expect(ddl.Metadata.Timeout).To(Equal(60))
+			Expect(ddl.ActionNames()).To(Equal([]string{"status", "restart"}))
+
+			status, err := ddl.ActionInterface("status")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Display).To(Equal("always"))
+			Expect(status.Input["service"].Type).To(Equal("string"))
+			Expect(status.Input["service"].Validation).To(Equal(`^[a-zA-Z\-_\d.@]+$`))
+			Expect(status.Input["service"].MaxLength).To(Equal(90))
+			Expect(status.Output["status"].Default).To(Equal("unknown"))
+
+			restart, err := ddl.ActionInterface("restart")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(restart.Display).To(Equal("failed"))
+			Expect(restart.Input["service"].Validation).To(Equal("shellsafe"))
+			Expect(restart.Input["signal"].Enum).To(Equal([]string{"HUP", "TERM"}))
+			Expect(restart.Input["signal"].Optional).To(BeTrue())
+			Expect(restart.Output["status"].Description).To(Equal("The status of the service after restarting"))
+			Expect(restart.Output["duration"].Type).To(Equal("float"))
+			Expect(restart.Aggregation).To(HaveLen(2))
+			Expect(restart.Aggregation[1].Function).To(Equal("stats"))
+			Expect(restart.Aggregation[1].Arguments).To(MatchJSON(`["duration", {"format": "%.2f", "percentiles": [50, 90]}]`))
+		})
+
+		It("Should reject unsupported Ruby", func() {
+			_, err := New(filepath.Join("testdata", "unsupported.ddl"))
+			Expect(err).To(MatchError("could not parse Ruby DDL testdata/unsupported.ddl: unsupported Ruby construct '[' on line 9"))
+		})
+	})
+
+	Describe("ParseRuby", func() {
+		It("Should parse DDLs produced by ToRuby", func() {
+			pkg, err := New(filepath.Join("testdata", "mcollective", "agent", "package.json"))
+			Expect(err).ToNot(HaveOccurred())
+
+			rb, err := pkg.ToRuby()
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := ParseRuby([]byte(rb))
+			Expect(err).ToNot(HaveOccurred())
+
+			expected, err := json.Marshal(pkg.Actions)
+			Expect(err).ToNot(HaveOccurred())
+			actual, err := json.Marshal(parsed.Actions)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(actual).To(MatchJSON(expected))
+			Expect(parsed.Metadata).To(Equal(pkg.Metadata))
+		})
+
+		It("Should support ranges, array items and hash fields", func() {
+			pkg, err := New(filepath.Join("testdata", "mcollective", "agent", "package.json"))
+			Expect(err).ToNot(HaveOccurred())
+
+			act, err := pkg.ActionInterface("install")
+			Expect(err).ToNot(HaveOccurred())
+
+			min := 1.0
+			max := 65535.0
+			act.Input["retries"] = &ActionInputItem{Prompt: "Retries", Description: "Retries", Type: "integer", Optional: true, Minimum: &min}
+			act.Input["tags"] = &ActionInputItem{Prompt: "Tags", Description: "Tags", Type: "array", Optional: true, MinLength: 1, MaxLength: 5, Items: &ActionInputItem{Type: "string", MaxLength: 10}}
+			act.Input["proxy"] = &ActionInputItem{Prompt: "Proxy", Description: "Proxy", Type: "hash", Optional: true, Properties: map[string]*ActionInputItem{
+				"port": {Type: "integer", Maximum: &max},
+			}}
+
+			rb, err := pkg.ToRuby()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rb).To(ContainSubstring(`        :minimum     => 1,`))
+			Expect(rb).To(ContainSubstring(`        :minlength   => 1,
+        :maxlength   => 5,
+        :items       => {'description' => '', 'maxlength' => 10, 'optional' => false, 'prompt' => '', 'type' => 'string'},`))
+			Expect(rb).To(ContainSubstring(`        :properties  => {'port' => {'description' => '', 'maximum' => 65535, 'optional' => false, 'prompt' => '', 'type' => 'integer'}},`))
+
+			parsed, err := ParseRuby([]byte(rb))
+			Expect(err).ToNot(HaveOccurred())
+
+			expected, err := json.Marshal(pkg.Actions)
+			Expect(err).ToNot(HaveOccurred())
+			actual, err := json.Marshal(parsed.Actions)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(actual).To(MatchJSON(expected))
+		})
+
+		It("Should require metadata", func() {
+			_, err := ParseRuby([]byte(`action "x", :description => "y" do
+end`))
+			Expect(err).To(MatchError("no metadata found"))
+		})
+
+		It("Should detect unterminated blocks", func() {
+			_, err := ParseRuby([]byte(`action "x", :description => "y" do`))
+			Expect(err).To(MatchError("unexpected end of file, expected 'end'"))
+		})
+	})
+
+	Describe("LoadableFile", func() {
+		It("Should prefer JSON DDLs over Ruby ones", func() {
+			files := make(map[string]string)
+
+			EachFile([]string{filepath.Join("testdata", "ruby")}, func(n, p string) bool {
+				files[n] = p
+				return false
+			})
+
+			Expect(files).To(Equal(map[string]string{
+				"echo":    filepath.Join("testdata", "ruby", "mcollective", "agent", "echo.json"),
+				"service": filepath.Join("testdata", "ruby", "mcollective", "agent", "service.ddl"),
+			}))
+		})
+	})
+})
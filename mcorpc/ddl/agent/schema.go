@@ -0,0 +1,280 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// SchemaURL is the JSON Schema that describes Agent DDL files
+const SchemaURL = "https://choria.io/schemas/mcorpc/ddl/v1/agent.json"
+
+// SchemaV2URL is a newer version of SchemaURL describing the same structure, DDLs using it are always
+// loaded with StrictValidators
+const SchemaV2URL = "https://choria.io/schemas/mcorpc/ddl/v2/agent.json"
+
+// legacySchemaURL is an older name for SchemaURL still found in some DDL files
+const legacySchemaURL = "https://choria.io/schemas/mcorpc/agent:1.json"
+
+var (
+	compiledSchemas = make(map[string]*gojsonschema.Schema)
+	schemasMu       = &sync.Mutex{}
+)
+
+// ValidateJSON validates a JSON DDL document against the embedded JSON Schema for the version
+// it declares, the returned error will list every problem found along with its location in the DDL
+func ValidateJSON(dat []byte) error {
+	declared := struct {
+		Schema string `json:"$schema"`
+	}{}
+
+	// invalid JSON is reported by the schema validation
+	json.Unmarshal(dat, &declared)
+
+	url := SchemaURL
+	if declared.Schema == SchemaV2URL {
+		url = SchemaV2URL
+	}
+
+	schema, err := compiledSchema(url)
+	if err != nil {
+		return fmt.Errorf("could not load the DDL schema %s: %s", url, err)
+	}
+
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(dat))
+	if err != nil {
+		return fmt.Errorf("could not validate DDL: %s", err)
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	errs := []string{}
+	for _, e := range result.Errors() {
+		errs = append(errs, fmt.Sprintf("%s: %s", e.Field(), e.Description()))
+	}
+
+	return fmt.Errorf("DDL does not match the schema %s: %s", url, strings.Join(errs, ", "))
+}
+
+// AgentSchema is the JSON Schema document for url, SchemaURL or SchemaV2URL
+//
+// Version 2 describes the same structure as version 1, the only difference is that
+// DDLs declaring it are loaded with StrictValidators
+func AgentSchema(url string) (string, error) {
+	switch url {
+	case SchemaURL:
+		return agentSchema, nil
+	case SchemaV2URL:
+		return strings.Replace(agentSchema, fmt.Sprintf(`"$id": "%s"`, SchemaURL), fmt.Sprintf(`"$id": "%s"`, SchemaV2URL), 1), nil
+	default:
+		return "", fmt.Errorf("unknown DDL schema %s", url)
+	}
+}
+
+func compiledSchema(url string) (*gojsonschema.Schema, error) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+
+	schema, ok := compiledSchemas[url]
+	if ok {
+		return schema, nil
+	}
+
+	doc, err := AgentSchema(url)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err = gojsonschema.NewSchema(gojsonschema.NewStringLoader(doc))
+	if err != nil {
+		return nil, err
+	}
+
+	compiledSchemas[url] = schema
+
+	return schema, nil
+}
+
+func validSchemaURL(schema string) bool {
+	switch schema {
+	case "", SchemaURL, SchemaV2URL, legacySchemaURL:
+		return true
+	default:
+		return false
+	}
+}
+
+var agentSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "https://choria.io/schemas/mcorpc/ddl/v1/agent.json",
+  "title": "Choria Agent DDL",
+  "type": "object",
+  "required": ["metadata", "actions"],
+  "additionalProperties": false,
+  "properties": {
+    "$schema": {"type": "string"},
+    "metadata": {"$ref": "#/definitions/metadata"},
+    "actions": {
+      "type": "array",
+      "items": {"$ref": "#/definitions/action"}
+    }
+  },
+  "definitions": {
+    "metadata": {
+      "type": "object",
+      "required": ["name", "description", "author", "license", "version", "url", "timeout"],
+      "additionalProperties": false,
+      "properties": {
+        "name": {"type": "string", "pattern": "^[a-z][a-z0-9_]*$"},
+        "description": {"type": "string", "minLength": 1},
+        "author": {"type": "string", "minLength": 1},
+        "license": {"type": "string", "minLength": 1},
+        "version": {"type": "string", "minLength": 1},
+        "url": {"type": "string", "minLength": 1},
+        "timeout": {"type": "integer", "minimum": 0},
+        "provider": {"type": "string"}
+      }
+    },
+    "action": {
+      "type": "object",
+      "required": ["action", "description", "input", "output"],
+      "additionalProperties": false,
+      "properties": {
+        "action": {"type": "string", "pattern": "^[a-z][a-z0-9_]*$"},
+        "description": {"type": "string"},
+        "display": {"type": "string", "enum": ["ok", "failed", "always"]},
+        "input": {
+          "type": "object",
+          "additionalProperties": {"$ref": "#/definitions/input"}
+        },
+        "output": {
+          "type": "object",
+          "additionalProperties": {"$ref": "#/definitions/output"}
+        },
+        "aggregate": {
+          "type": "array",
+          "items": {"$ref": "#/definitions/aggregate"}
+        }
+      }
+    },
+    "input": {
+      "type": "object",
+      "required": ["prompt", "description", "type", "optional"],
+      "additionalProperties": false,
+      "properties": {
+        "prompt": {"type": "string"},
+        "description": {"type": "string"},
+        "type": {"$ref": "#/definitions/type"},
+        "default": {},
+        "optional": {"type": "boolean"},
+        "validation": {"type": "string"},
+        "maxlength": {"type": "integer", "minimum": 0},
+        "minlength": {"type": "integer", "minimum": 0},
+        "minimum": {"type": "number"},
+        "maximum": {"type": "number"},
+        "list": {
+          "type": "array",
+          "items": {"type": "string"}
+        },
+        "items": {"$ref": "#/definitions/field"},
+        "properties": {
+          "type": "object",
+          "additionalProperties": {"$ref": "#/definitions/field"}
+        }
+      },
+      "allOf": [{"$ref": "#/definitions/input_rules"}]
+    },
+    "field": {
+      "description": "An array item or hash field, these are inputs that do not require a prompt or description",
+      "type": "object",
+      "required": ["type"],
+      "additionalProperties": false,
+      "properties": {
+        "prompt": {"type": "string"},
+        "description": {"type": "string"},
+        "type": {"$ref": "#/definitions/type"},
+        "default": {},
+        "optional": {"type": "boolean"},
+        "validation": {"type": "string"},
+        "maxlength": {"type": "integer", "minimum": 0},
+        "minlength": {"type": "integer", "minimum": 0},
+        "minimum": {"type": "number"},
+        "maximum": {"type": "number"},
+        "list": {
+          "type": "array",
+          "items": {"type": "string"}
+        },
+        "items": {"$ref": "#/definitions/field"},
+        "properties": {
+          "type": "object",
+          "additionalProperties": {"$ref": "#/definitions/field"}
+        }
+      },
+      "allOf": [{"$ref": "#/definitions/input_rules"}]
+    },
+    "input_rules": {
+      "allOf": [
+        {
+          "if": {"required": ["list"]},
+          "then": {"properties": {"type": {"const": "list"}}}
+        },
+        {
+          "if": {"required": ["type"], "properties": {"type": {"const": "list"}}},
+          "then": {
+            "required": ["list"],
+            "properties": {"list": {"minItems": 1}}
+          }
+        },
+        {
+          "if": {"anyOf": [{"required": ["minimum"]}, {"required": ["maximum"]}]},
+          "then": {"properties": {"type": {"enum": ["integer", "float", "number"]}}}
+        },
+        {
+          "if": {"required": ["items"]},
+          "then": {"properties": {"type": {"enum": ["array", "Array"]}}}
+        },
+        {
+          "if": {"required": ["properties"]},
+          "then": {"properties": {"type": {"enum": ["hash", "Hash"]}}}
+        }
+      ]
+    },
+    "output": {
+      "type": "object",
+      "required": ["description", "display_as"],
+      "additionalProperties": false,
+      "properties": {
+        "description": {"type": "string"},
+        "display_as": {"type": "string"},
+        "default": {},
+        "type": {"$ref": "#/definitions/type"}
+      }
+    },
+    "aggregate": {
+      "type": "object",
+      "required": ["function", "args"],
+      "additionalProperties": false,
+      "properties": {
+        "function": {"type": "string", "minLength": 1},
+        "args": {
+          "type": "array",
+          "minItems": 1,
+          "items": [
+            {"type": "string", "minLength": 1},
+            {"type": "object"}
+          ],
+          "additionalItems": false
+        }
+      }
+    },
+    "type": {
+      "type": "string",
+      "enum": ["string", "integer", "float", "number", "boolean", "list", "hash", "Hash", "array", "Array"]
+    }
+  }
+}`
@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("McoRPC/DDL/Agent/Schema", func() {
+	var ddl string
+
+	BeforeEach(func() {
+		ddl = `{
+  "$schema": "https://choria.io/schemas/mcorpc/ddl/v1/agent.json",
+  "metadata": {"name": "test", "description": "Test", "author": "Test", "license": "Apache-2.0", "version": "1.0.0", "url": "https://choria.io", "timeout": 10},
+  "actions": [
+    {
+      "action": "test",
+      "description": "Test action",
+      "input": {
+        "mode": {"prompt": "Mode", "description": "Mode", "type": "%s", "optional": true %s}
+      },
+      "output": {
+        "out": {"description": "Output", "display_as": "Out"}
+      },
+      "aggregate": [%s]
+    }
+  ]
+}`
+	})
+
+	Describe("ValidateJSON", func() {
+		It("Should accept valid DDLs", func() {
+			dat, err := ioutil.ReadFile(path.Join("testdata", "mcollective", "agent", "package.json"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ValidateJSON(dat)).ToNot(HaveOccurred())
+
+			Expect(ValidateJSON([]byte(fmt.Sprintf(ddl, "list", `, "list": ["a", "b"]`, `{"function": "summary", "args": ["out", {"format": "%s"}]}`)))).ToNot(HaveOccurred())
+		})
+
+		It("Should reject unknown input types", func() {
+			err := ValidateJSON([]byte(fmt.Sprintf(ddl, "strng", "", "")))
+			Expect(err).To(MatchError(ContainSubstring("actions.0.input.mode.type: actions.0.input.mode.type must be one of the following")))
+		})
+
+		It("Should reject unknown properties", func() {
+			err := ValidateJSON([]byte(fmt.Sprintf(ddl, "string", `, "optinal": true`, "")))
+			Expect(err).To(MatchError(ContainSubstring("actions.0.input.mode: Additional property optinal is not allowed")))
+		})
+
+		It("Should reject enums on non list types", func() {
+			err := ValidateJSON([]byte(fmt.Sprintf(ddl, "string", `, "list": ["a"]`, "")))
+			Expect(err).To(MatchError(ContainSubstring(`actions.0.input.mode.type: actions.0.input.mode.type does not match: "list"`)))
+		})
+
+		It("Should require enums on list types", func() {
+			err := ValidateJSON([]byte(fmt.Sprintf(ddl, "list", "", "")))
+			Expect(err).To(MatchError(ContainSubstring("actions.0.input.mode: list is required")))
+		})
+
+		It("Should accept ranges, array items and hash fields", func() {
+			Expect(ValidateJSON([]byte(fmt.Sprintf(ddl, "integer", `, "minimum": 1, "maximum": 10`, "")))).ToNot(HaveOccurred())
+			Expect(ValidateJSON([]byte(fmt.Sprintf(ddl, "array", `, "minlength": 1, "maxlength": 5, "items": {"type": "list", "list": ["a", "b"]}`, "")))).ToNot(HaveOccurred())
+			Expect(ValidateJSON([]byte(fmt.Sprintf(ddl, "hash", `, "properties": {"port": {"type": "integer", "maximum": 65535}, "tags": {"type": "array", "optional": true, "items": {"type": "string"}}}`, "")))).ToNot(HaveOccurred())
+		})
+
+		It("Should reject constraints that do not match the type", func() {
+			err := ValidateJSON([]byte(fmt.Sprintf(ddl, "string", `, "minimum": 1`, "")))
+			Expect(err).To(MatchError(ContainSubstring("actions.0.input.mode.type: actions.0.input.mode.type must be one of the following: \"integer\", \"float\", \"number\"")))
+
+			err = ValidateJSON([]byte(fmt.Sprintf(ddl, "hash", `, "items": {"type": "string"}`, "")))
+			Expect(err).To(MatchError(ContainSubstring("actions.0.input.mode.type: actions.0.input.mode.type must be one of the following: \"array\", \"Array\"")))
+
+			err = ValidateJSON([]byte(fmt.Sprintf(ddl, "hash", `, "properties": {"port": {"description": "Port"}}`, "")))
+			Expect(err).To(MatchError(ContainSubstring("actions.0.input.mode.properties.port: type is required")))
+		})
+
+		It("Should reject malformed aggregates", func() {
+			err := ValidateJSON([]byte(fmt.Sprintf(ddl, "string", "", `{"function": "summary", "args": [1]}`)))
+			Expect(err).To(MatchError(ContainSubstring("actions.0.aggregate.0.args.0: Invalid type. Expected: string, given: integer")))
+
+			err = ValidateJSON([]byte(fmt.Sprintf(ddl, "string", "", `{"function": "summary"}`)))
+			Expect(err).To(MatchError(ContainSubstring("actions.0.aggregate.0: args is required")))
+		})
+	})
+
+	Describe("AgentSchema", func() {
+		It("Should report the declared schema", func() {
+			v2 := strings.Replace(fmt.Sprintf(ddl, "strng", "", ""), SchemaURL, SchemaV2URL, 1)
+			err := ValidateJSON([]byte(v2))
+			Expect(err).To(MatchError(ContainSubstring("DDL does not match the schema " + SchemaV2URL)))
+		})
+
+		It("Should give each version its own id", func() {
+			schema, err := AgentSchema(SchemaV2URL)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(schema).To(ContainSubstring(`"$id": "` + SchemaV2URL + `"`))
+			Expect(schema).ToNot(ContainSubstring(SchemaURL))
+
+			_, err = AgentSchema("https://example.net/schema.json")
+			Expect(err).To(MatchError("unknown DDL schema https://example.net/schema.json"))
+		})
+	})
+
+	Describe("New", func() {
+		It("Should fail for DDLs that do not match the schema", func() {
+			d, err := New(path.Join("testdata", "invalid_schema.json"))
+			Expect(err).To(MatchError(ContainSubstring("invalid DDL testdata/invalid_schema.json: DDL does not match the schema https://choria.io/schemas/mcorpc/ddl/v1/agent.json")))
+			Expect(err).To(MatchError(ContainSubstring("metadata: url is required")))
+			Expect(err).To(MatchError(ContainSubstring("actions.0.input.name: optional is required")))
+			Expect(err).To(MatchError(ContainSubstring("actions.0.input.name: Additional property optinal is not allowed")))
+			Expect(d).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,265 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/choria-io/go-validator/duration"
+	"github.com/choria-io/go-validator/ipaddress"
+	"github.com/choria-io/go-validator/ipv4"
+	"github.com/choria-io/go-validator/ipv6"
+	"github.com/choria-io/go-validator/shellsafe"
+)
+
+// StringValidator validates a string input, a nil error means the value is valid
+type StringValidator func(value string) error
+
+var (
+	validators = map[string]StringValidator{
+		"shellsafe":    boolValidator(shellsafe.Validate),
+		"ipv4address":  boolValidator(ipv4.ValidateString),
+		"ipv6address":  boolValidator(ipv6.ValidateString),
+		"ipaddress":    boolValidator(ipaddress.ValidateString),
+		"hostname":     validateHostname,
+		"fqdn":         validateFQDN,
+		"cidr":         validateCIDR,
+		"url":          validateURL,
+		"email":        validateEmail,
+		"semver":       validateSemVer,
+		"absolutepath": validateAbsolutePath,
+		"relativepath": validateRelativePath,
+		"uuid":         validateUUID,
+		"duration":     boolValidator(duration.ValidateString),
+		"json":         validateJSON,
+	}
+
+	validatorsMu = &sync.Mutex{}
+
+	hostnameLabelRe = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+	semverRe        = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+	uuidRe          = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	validatorNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+// StrictValidatorsOption is the server configuration option that enables StrictValidators for every agent
+const StrictValidatorsOption = "plugin.choria.ddl.strict_validators"
+
+// RegisterValidator adds a named string validator that DDLs can use in the validation property of
+// string inputs, names must be lower case and the built in validators cannot be replaced
+func RegisterValidator(name string, v StringValidator) error {
+	if !validatorNameRe.MatchString(name) {
+		return fmt.Errorf("invalid validator name '%s', names must match %s", name, validatorNameRe.String())
+	}
+
+	if v == nil {
+		return fmt.Errorf("no validator supplied for '%s'", name)
+	}
+
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+
+	if _, ok := validators[name]; ok {
+		return fmt.Errorf("a validator called '%s' is already registered", name)
+	}
+
+	validators[name] = v
+
+	return nil
+}
+
+// ValidatorNames is the sorted list of known named validators
+func ValidatorNames() []string {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+
+	names := []string{}
+	for name := range validators {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func namedValidator(name string) (StringValidator, bool) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+
+	v, ok := validators[name]
+
+	return v, ok
+}
+
+// boolValidator adapts the go-validator style functions to a StringValidator
+func boolValidator(f func(string) (bool, error)) StringValidator {
+	return func(value string) error {
+		_, err := f(value)
+		return err
+	}
+}
+
+func validateHostname(value string) error {
+	name := strings.TrimSuffix(value, ".")
+
+	if name == "" || len(name) > 253 {
+		return fmt.Errorf("%s is not a valid hostname", value)
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if !hostnameLabelRe.MatchString(label) {
+			return fmt.Errorf("%s is not a valid hostname", value)
+		}
+	}
+
+	return nil
+}
+
+func validateFQDN(value string) error {
+	name := strings.TrimSuffix(value, ".")
+	labels := strings.Split(name, ".")
+
+	if len(labels) < 2 || validateHostname(value) != nil {
+		return fmt.Errorf("%s is not a fully qualified domain name", value)
+	}
+
+	tld := labels[len(labels)-1]
+	if strings.Trim(tld, "0123456789") == "" {
+		return fmt.Errorf("%s is not a fully qualified domain name", value)
+	}
+
+	return nil
+}
+
+func validateCIDR(value string) error {
+	_, _, err := net.ParseCIDR(value)
+	if err != nil {
+		return fmt.Errorf("%s is not a CIDR network", value)
+	}
+
+	return nil
+}
+
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%s is not a valid URL", value)
+	}
+
+	return nil
+}
+
+func validateEmail(value string) error {
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		return fmt.Errorf("%s is not a valid email address", value)
+	}
+
+	return nil
+}
+
+func validateSemVer(value string) error {
+	if !semverRe.MatchString(value) {
+		return fmt.Errorf("%s is not a valid semantic version", value)
+	}
+
+	return nil
+}
+
+func validateAbsolutePath(value string) error {
+	if strings.ContainsRune(value, 0) || !filepath.IsAbs(value) {
+		return fmt.Errorf("%s is not an absolute path", value)
+	}
+
+	return nil
+}
+
+func validateRelativePath(value string) error {
+	if value == "" || strings.ContainsRune(value, 0) || filepath.IsAbs(value) {
+		return fmt.Errorf("%s is not a relative path", value)
+	}
+
+	return nil
+}
+
+func validateUUID(value string) error {
+	if !uuidRe.MatchString(value) {
+		return fmt.Errorf("%s is not a valid UUID", value)
+	}
+
+	return nil
+}
+
+func validateJSON(value string) error {
+	if !json.Valid([]byte(value)) {
+		return fmt.Errorf("is not valid JSON")
+	}
+
+	return nil
+}
+
+// UnknownValidators describes every input, including array items and hash fields, that uses a named validator that is not registered
+func (d *DDL) UnknownValidators() []string {
+	unknown := []string{}
+
+	for _, act := range d.Actions {
+		for _, name := range act.InputNames() {
+			unknown = append(unknown, act.Input[name].unknownValidators(act.Name, name)...)
+		}
+	}
+
+	return unknown
+}
+
+func (i *ActionInputItem) unknownValidators(action string, path string) []string {
+	unknown := []string{}
+
+	if i.Validation != "" && namedValidatorRe.MatchString(i.Validation) {
+		if _, ok := namedValidator(i.Validation); !ok {
+			unknown = append(unknown, fmt.Sprintf("input %s of action %s uses unknown validator '%s'", path, action, i.Validation))
+		}
+	}
+
+	if i.Items != nil {
+		unknown = append(unknown, i.Items.unknownValidators(action, path+".items")...)
+	}
+
+	fields := []string{}
+	for name := range i.Properties {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+
+	for _, name := range fields {
+		unknown = append(unknown, i.Properties[name].unknownValidators(action, path+"."+name)...)
+	}
+
+	return unknown
+}
+
+// checkValidators fails for unknown validators when strict validation is enabled or the DDL uses SchemaV2URL
+func (d *DDL) checkValidators(opts ...LoadOption) error {
+	lopts := &loadOptions{}
+	for _, opt := range opts {
+		opt(lopts)
+	}
+
+	if !lopts.strictValidators && d.Schema != SchemaV2URL {
+		return nil
+	}
+
+	unknown := d.UnknownValidators()
+	if len(unknown) > 0 {
+		return fmt.Errorf("%s", strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
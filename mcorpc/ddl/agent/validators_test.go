@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	config "github.com/choria-io/go-config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("McoRPC/DDL/Agent/Validators", func() {
+	Describe("Built in validators", func() {
+		valid := map[string][]string{
+			"hostname":     {"web1", "web-1.example.net", "example.net."},
+			"fqdn":         {"web1.example.net", "example.net."},
+			"cidr":         {"192.168.0.0/16", "2001:db8::/32"},
+			"url":          {"https://choria.io/docs", "nats://broker:4222"},
+			"email":        {"rip@devco.net"},
+			"semver":       {"1.0.0", "1.2.3-rc.1+build.5"},
+			"absolutepath": {"/etc/choria/server.conf"},
+			"relativepath": {"choria/server.conf", "./server.conf"},
+			"uuid":         {"2a1c4b3e-6f0d-4e7a-9b2c-8d5e1f3a7c90"},
+			"duration":     {"10s", "1h30m"},
+			"json":         {`{"a":1}`, `[1,2]`, `"x"`},
+		}
+
+		invalid := map[string][]string{
+			"hostname":     {"-web", "web_1", "a..b", strings.Repeat("a", 64)},
+			"fqdn":         {"web1", "192.168.1.1"},
+			"cidr":         {"192.168.0.0", "192.168.0.0/33"},
+			"url":          {"choria.io", "/docs"},
+			"email":        {"rip", "R.I.Pienaar <rip@devco.net>"},
+			"semver":       {"1.0", "v1.0.0", "01.0.0"},
+			"absolutepath": {"etc/choria"},
+			"relativepath": {"/etc/choria", ""},
+			"uuid":         {"2a1c4b3e6f0d4e7a9b2c8d5e1f3a7c90", "not-a-uuid"},
+			"duration":     {"10", "1 hour"},
+			"json":         {`{"a":`, "plain"},
+		}
+
+		for name, values := range valid {
+			for _, value := range values {
+				name, value := name, value
+
+				It(fmt.Sprintf("Should accept '%s' as %s", value, name), func() {
+					warnings, err := validateStringValidation(name, value)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(warnings).To(BeEmpty())
+				})
+			}
+		}
+
+		for name, values := range invalid {
+			for _, value := range values {
+				name, value := name, value
+
+				It(fmt.Sprintf("Should reject '%s' as %s", value, name), func() {
+					_, err := validateStringValidation(name, value)
+					Expect(err).To(HaveOccurred())
+				})
+			}
+		}
+
+		It("Should produce helpful errors", func() {
+			_, err := validateStringValidation("fqdn", "web1")
+			Expect(err).To(MatchError("web1 is not a fully qualified domain name"))
+		})
+
+		It("Should still warn about unknown validators", func() {
+			warnings, err := validateStringValidation("unknown", "x")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(warnings).To(Equal([]string{"Unsupported validator 'unknown'"}))
+		})
+	})
+
+	Describe("RegisterValidator", func() {
+		It("Should register new validators", func() {
+			defer func() {
+				validatorsMu.Lock()
+				delete(validators, "test_even_length")
+				validatorsMu.Unlock()
+			}()
+
+			err := RegisterValidator("test_even_length", func(v string) error {
+				if len(v)%2 != 0 {
+					return fmt.Errorf("should have an even length")
+				}
+
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ValidatorNames()).To(ContainElement("test_even_length"))
+
+			act := &Action{Input: map[string]*ActionInputItem{
+				"name": {Type: "string", MaxLength: 10, Validation: "test_even_length"},
+			}}
+
+			_, err = act.ValidateInputValue("name", "ab")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = act.ValidateInputValue("name", "abc")
+			Expect(err).To(MatchError("should have an even length"))
+
+			err = RegisterValidator("test_even_length", func(string) error { return nil })
+			Expect(err).To(MatchError("a validator called 'test_even_length' is already registered"))
+		})
+
+		It("Should not replace built in validators", func() {
+			err := RegisterValidator("shellsafe", func(string) error { return nil })
+			Expect(err).To(MatchError("a validator called 'shellsafe' is already registered"))
+		})
+
+		It("Should validate names", func() {
+			err := RegisterValidator("Bad", func(string) error { return nil })
+			Expect(err).To(MatchError("invalid validator name 'Bad', names must match ^[a-z][a-z0-9_]*$"))
+
+			err = RegisterValidator("nil_validator", nil)
+			Expect(err).To(MatchError("no validator supplied for 'nil_validator'"))
+		})
+	})
+
+	Describe("Strict validators", func() {
+		It("Should accept unknown validators by default", func() {
+			ddl, err := New(filepath.Join("testdata", "unknown_validator.json"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ddl.UnknownValidators()).To(Equal([]string{
+				"input name of action resolve uses unknown validator 'dnsname'",
+				"input servers.items of action resolve uses unknown validator 'nameserver'",
+			}))
+		})
+
+		It("Should reject unknown validators in strict mode", func() {
+			_, err := New(filepath.Join("testdata", "unknown_validator.json"), StrictValidators(true))
+			Expect(err).To(MatchError("invalid DDL testdata/unknown_validator.json: input name of action resolve uses unknown validator 'dnsname', input servers.items of action resolve uses unknown validator 'nameserver'"))
+
+			_, err = New(filepath.Join("testdata", "mcollective", "agent", "package.json"), StrictValidators(true))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Should always be strict for v2 DDLs", func() {
+			_, err := New(filepath.Join("testdata", "unknown_validator_v2.json"))
+			Expect(err).To(MatchError("invalid DDL testdata/unknown_validator_v2.json: input name of action resolve uses unknown validator 'dnsname', input servers.items of action resolve uses unknown validator 'nameserver'"))
+		})
+
+		It("Should support enabling strict mode in the configuration", func() {
+			cfg, err := config.NewDefaultConfig()
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = New(filepath.Join("testdata", "unknown_validator.json"), StrictValidatorsFromConfig(cfg))
+			Expect(err).ToNot(HaveOccurred())
+
+			cfg.SetOption(StrictValidatorsOption, "true")
+			_, err = New(filepath.Join("testdata", "unknown_validator.json"), StrictValidatorsFromConfig(cfg))
+			Expect(err).To(HaveOccurred())
+
+			_, err = New(filepath.Join("testdata", "unknown_validator.json"), StrictValidatorsFromConfig(nil))
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
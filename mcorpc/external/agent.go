@@ -156,6 +156,12 @@ func (p *Provider) externalAction(ctx context.Context, req *mcorpc.Request, repl
 		return
 	}
 
+	err = p.validateReply(ddl, req.Action, reply, agent.Log)
+	if err != nil && reply.Statuscode == mcorpc.OK {
+		reply.Statuscode = mcorpc.UnknownError
+		reply.Statusmsg = fmt.Sprintf("Reply does not match the DDL: %s", err)
+	}
+
 	err = p.setReplyDefaults(ddl, req.Action, reply)
 	if err != nil {
 		p.abortAction(fmt.Sprintf("Could not set reply defaults: %s", err), agent, reply)
@@ -183,6 +189,42 @@ func (p *Provider) validateRequest(ddl *agentddl.DDL, req *mcorpc.Request, log *
 	return nil
 }
 
+// validateReply checks the reply data against the DDL outputs, an error is only returned in strict mode
+func (p *Provider) validateReply(ddl *agentddl.DDL, action string, reply *mcorpc.Reply, log *logrus.Entry) error {
+	validation := agentddl.ReplyValidationFromConfig(p.cfg)
+
+	actint, err := ddl.ActionInterface(action)
+	if err != nil {
+		if validation.Strict {
+			return fmt.Errorf("could not load action: %s", err)
+		}
+
+		log.Warnf("Could not validate the reply from %s#%s: %s", ddl.Metadata.Name, action, err)
+
+		return nil
+	}
+
+	result, ok := reply.Data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	warnings, err := actint.ValidateReplyData(result, validation.Coerce)
+	for _, w := range warnings {
+		log.Warnf("Validation of the reply from %s#%s returned a warning: %s", ddl.Metadata.Name, action, w)
+	}
+
+	if err != nil {
+		if validation.Strict {
+			return err
+		}
+
+		log.Warnf("Reply from %s#%s does not match the DDL: %s", ddl.Metadata.Name, action, err)
+	}
+
+	return nil
+}
+
 func (p *Provider) setReplyDefaults(ddl *agentddl.DDL, action string, reply *mcorpc.Reply) error {
 	actint, err := ddl.ActionInterface(action)
 	if err != nil {
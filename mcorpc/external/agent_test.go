@@ -249,4 +249,32 @@ var _ = Describe("McoRPC/External", func() {
 			Expect(rep.Data.(map[string]interface{})["optional"].(string)).To(Equal("optional default"))
 		})
 	})
+
+	Describe("validateReply", func() {
+		var ddl *addl.DDL
+
+		BeforeEach(func() {
+			ddl = &addl.DDL{
+				Metadata: &agents.Metadata{Name: "ginkgo"},
+				Actions: []*addl.Action{
+					&addl.Action{
+						Name:   "ping",
+						Output: map[string]*addl.ActionOutputItem{"count": &addl.ActionOutputItem{Type: "integer"}},
+					},
+				},
+			}
+		})
+
+		It("Should only fail in strict mode", func() {
+			rep := &mcorpc.Reply{Data: map[string]interface{}{"count": "x"}}
+
+			Expect(prov.validateReply(ddl, "ping", rep, logger)).ToNot(HaveOccurred())
+			Expect(prov.validateReply(ddl, "unknown", rep, logger)).ToNot(HaveOccurred())
+
+			cfg.SetOption(addl.StrictRepliesOption, "true")
+
+			Expect(prov.validateReply(ddl, "ping", rep, logger)).To(HaveOccurred())
+			Expect(prov.validateReply(ddl, "unknown", rep, logger)).To(MatchError(HavePrefix("could not load action: ")))
+		})
+	})
 })
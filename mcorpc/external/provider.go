@@ -3,8 +3,6 @@ package external
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/choria-io/go-choria/choria"
@@ -83,52 +81,33 @@ func (p *Provider) agentDDL(a string) (*agent.DDL, bool) {
 }
 
 func (p *Provider) eachAgent(cb func(ddl *agent.DDL)) {
-	for _, dir := range p.cfg.Choria.RubyLibdir {
-		agentsdir := filepath.Join(dir, "mcollective", "agent")
+	p.log.Debugf("Attempting to load External agents from %s", strings.Join(p.cfg.Choria.RubyLibdir, ", "))
 
-		p.log.Debugf("Attempting to load External agents from %s", agentsdir)
+	registry := agent.SharedRegistry(p.cfg.Choria.RubyLibdir, agent.StrictValidatorsFromConfig(p.cfg))
 
-		err := filepath.Walk(agentsdir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-
-			if info.IsDir() {
-				return nil
-			}
-
-			fname := info.Name()
-			ext := filepath.Ext(fname)
-			name := strings.TrimSuffix(fname, ext)
-
-			if ext != ".json" {
-				return nil
-			}
-
-			if !shouldLoadAgent(name) {
-				p.log.Warnf("External agents are not allowed to supply an agent called '%s', skipping", name)
-				return nil
-			}
-
-			p.log.Debugf("Attempting to load %s as an agent DDL", path)
-
-			ddl, err := agent.New(path)
-			if err != nil {
-				p.log.Errorf("Could not load external agent DDL %s: %s", path, err)
-				return nil
-			}
+	for _, name := range registry.Names() {
+		if !shouldLoadAgent(name) {
+			p.log.Warnf("External agents are not allowed to supply an agent called '%s', skipping", name)
+			continue
+		}
 
-			if ddl.Metadata.Provider == "external" {
-				cb(ddl)
-			}
+		ddl, shadowed, err := registry.FindProvider(name, "external")
+		if err != nil {
+			p.log.Errorf("Could not load external agent DDL for %s: %s", name, err)
+			continue
+		}
 
-			return nil
-		})
+		if ddl == nil {
+			continue
+		}
 
-		if err != nil {
-			p.log.Errorf("Could not find agents in %s: %s", agentsdir, err)
+		for _, path := range shadowed {
+			p.log.Warnf("Ignoring %s as the %s agent is already provided by %s", path, name, ddl.SourceLocation)
 		}
 
+		p.log.Debugf("Loaded %s as an agent DDL", ddl.SourceLocation)
+
+		cb(ddl)
 	}
 }
 
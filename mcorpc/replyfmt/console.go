@@ -81,6 +81,12 @@ func (c *ConsoleFormatter) FormatAggregates(w *bufio.Writer, action *agent.Actio
 		output, ok := action.Output[k]
 		if ok {
 			descr = output.DisplayAs
+		} else if parts := strings.SplitN(k, ".", 2); len(parts) == 2 {
+			// aggregates on paths into outputs like packages.*.version
+			output, ok = action.Output[parts[0]]
+			if ok {
+				descr = fmt.Sprintf("%s (%s)", output.DisplayAs, parts[1])
+			}
 		}
 
 		if c.disableColor {
@@ -109,6 +115,66 @@ func (c *ConsoleFormatter) FormatAggregates(w *bufio.Writer, action *agent.Actio
 	return nil
 }
 
+// FormatInputs describes the inputs of an action along with their types and constraints, the fields of hash inputs are shown nested below them
+func (c *ConsoleFormatter) FormatInputs(w *bufio.Writer, action *agent.Action) error {
+	defer w.Flush()
+
+	if c.disableColor {
+		fmt.Fprintf(w, "Inputs for %s:\n\n", action.Name)
+	} else {
+		fmt.Fprintln(w, color.HiWhiteString("Inputs for %s:\n", action.Name))
+	}
+
+	if len(action.Input) == 0 {
+		if c.disableColor {
+			fmt.Fprintf(w, "   No inputs\n\n")
+		} else {
+			fmt.Fprintf(w, "   %s\n\n", color.YellowString("No inputs"))
+		}
+
+		return nil
+	}
+
+	for _, name := range action.InputNames() {
+		c.inputPrinter(w, name, action.Input[name], "   ")
+		fmt.Fprintln(w)
+	}
+
+	return nil
+}
+
+func (c *ConsoleFormatter) inputPrinter(w *bufio.Writer, name string, input *agent.ActionInputItem, indent string) {
+	required := "required"
+	if input.Optional {
+		required = "optional"
+	}
+
+	fmt.Fprintf(w, "%s%s (%s, %s)\n", indent, name, input.Type, required)
+
+	if input.Description != "" {
+		fmt.Fprintf(w, "%s   %s\n", indent, input.Description)
+	}
+
+	constraints := input.Constraints()
+	if len(constraints) > 0 {
+		fmt.Fprintf(w, "%s   Constraints: %s\n", indent, strings.Join(constraints, ", "))
+	}
+
+	if input.Default != nil {
+		fmt.Fprintf(w, "%s   Default: %v\n", indent, input.Default)
+	}
+
+	var fields []string
+	for field := range input.Properties {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		c.inputPrinter(w, field, input.Properties[field], indent+"   ")
+	}
+}
+
 func (c *ConsoleFormatter) FormatReply(w *bufio.Writer, action *agent.Action, sender string, reply *client.RPCReply) error {
 	c.out = w
 	c.actionInterface = action
@@ -19,6 +19,11 @@ type Formatter interface {
 	SetDisplay(mode DisplayMode)
 }
 
+// InputFormatter is a Formatter that can also describe the inputs of an action
+type InputFormatter interface {
+	FormatInputs(w *bufio.Writer, action *agent.Action) error
+}
+
 // DisplayMode overrides the DDL display hints
 type DisplayMode uint8
 
@@ -96,3 +101,18 @@ func FormatReply(w *bufio.Writer, f OutputFormat, action *agent.Action, sender s
 
 	return rf.FormatReply(w, action, sender, reply)
 }
+
+// FormatInputs describes the inputs of action using formatters that implement InputFormatter
+func FormatInputs(w *bufio.Writer, f OutputFormat, action *agent.Action, opts ...Option) error {
+	rf, err := formatter(f, opts...)
+	if err != nil {
+		return err
+	}
+
+	inf, ok := rf.(InputFormatter)
+	if !ok {
+		return fmt.Errorf("formatter does not support formatting inputs")
+	}
+
+	return inf.FormatInputs(w, action)
+}
@@ -58,7 +58,7 @@ func NewRubyAgent(ddl *agent.DDL, mgr server.AgentManager) (*mcorpc.Agent, error
 			return nil, err
 		}
 
-		agent.MustRegisterAction(actint.Name, rubyAction)
+		agent.MustRegisterAction(actint.Name, validatedAction(actint, agent.Config, rubyAction))
 	}
 
 	return agent, nil
@@ -157,6 +157,42 @@ func rubyAction(ctx context.Context, req *mcorpc.Request, reply *mcorpc.Reply, a
 	}()
 }
 
+// validatedAction checks the reply data of action against the DDL outputs, in strict mode replies
+// that do not match are marked as failed
+func validatedAction(actint *agent.Action, cfg *config.Config, action mcorpc.Action) mcorpc.Action {
+	validation := agent.ReplyValidationFromConfig(cfg)
+
+	return func(ctx context.Context, req *mcorpc.Request, reply *mcorpc.Reply, agent *mcorpc.Agent, conn choria.ConnectorInfo) {
+		action(ctx, req, reply, agent, conn)
+
+		if reply.Statuscode != mcorpc.OK {
+			return
+		}
+
+		result, ok := reply.Data.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		warnings, err := actint.ValidateReplyData(result, validation.Coerce)
+		for _, w := range warnings {
+			agent.Log.Warnf("Validation of the reply from %s#%s returned a warning: %s", req.Agent, req.Action, w)
+		}
+
+		if err == nil {
+			return
+		}
+
+		if validation.Strict {
+			reply.Statuscode = mcorpc.UnknownError
+			reply.Statusmsg = fmt.Sprintf("Reply does not match the DDL: %s", err)
+			return
+		}
+
+		agent.Log.Warnf("Reply from %s#%s does not match the DDL: %s", req.Agent, req.Action, err)
+	}
+}
+
 func newShimRequest(req *mcorpc.Request) ([]byte, error) {
 	sr := ShimRequest{
 		Action: req.Action,
@@ -127,6 +127,53 @@ var _ = Describe("McoRPC/Ruby", func() {
 		})
 	})
 
+	Describe("validatedAction", func() {
+		var (
+			act *ddl.Action
+			rep *mcorpc.Reply
+			req *mcorpc.Request
+		)
+
+		BeforeEach(func() {
+			d, err := ddl.New("testdata/lib1/mcollective/agent/one.json")
+			Expect(err).ToNot(HaveOccurred())
+
+			agent, err = NewRubyAgent(d, agentMgr)
+			Expect(err).ToNot(HaveOccurred())
+
+			act, err = d.ActionInterface("count")
+			Expect(err).ToNot(HaveOccurred())
+			act.Output["exitcode"].Type = "integer"
+
+			req = &mcorpc.Request{Agent: "one", Action: "count"}
+			rep = &mcorpc.Reply{}
+		})
+
+		action := func(ctx context.Context, req *mcorpc.Request, reply *mcorpc.Reply, agent *mcorpc.Agent, conn choria.ConnectorInfo) {
+			reply.Data = map[string]interface{}{"exitcode": "0"}
+		}
+
+		It("Should accept replies that do not match by default", func() {
+			validatedAction(act, cfg, action)(context.Background(), req, rep, agent, nil)
+			Expect(rep.Statuscode).To(Equal(mcorpc.OK))
+			Expect(rep.Data).To(Equal(map[string]interface{}{"exitcode": "0"}))
+		})
+
+		It("Should support coercing replies", func() {
+			cfg.SetOption(ddl.CoerceRepliesOption, "true")
+			validatedAction(act, cfg, action)(context.Background(), req, rep, agent, nil)
+			Expect(rep.Statuscode).To(Equal(mcorpc.OK))
+			Expect(rep.Data).To(Equal(map[string]interface{}{"exitcode": int64(0)}))
+		})
+
+		It("Should fail replies that do not match in strict mode", func() {
+			cfg.SetOption(ddl.StrictRepliesOption, "true")
+			validatedAction(act, cfg, action)(context.Background(), req, rep, agent, nil)
+			Expect(rep.Statuscode).To(Equal(mcorpc.UnknownError))
+			Expect(rep.Statusmsg).To(Equal("Reply does not match the DDL: output 'exitcode' should be of type integer but got string"))
+		})
+	})
+
 	Describe("activationCheck", func() {
 		var (
 			d   *ddl.DDL
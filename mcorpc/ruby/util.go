@@ -15,59 +15,40 @@ func (p *Provider) loadAgents(libdirs []string) {
 }
 
 func (p *Provider) eachAgent(libdirs []string, cb func(ddl *agentddl.DDL)) {
-	for _, dir := range libdirs {
-		agentsdir := filepath.Join(dir, "mcollective", "agent")
+	p.log.Debugf("Attempting to load Ruby agents from %s", strings.Join(libdirs, ", "))
 
-		p.log.Debugf("Attempting to load Ruby agents from %s", agentsdir)
+	registry := agentddl.SharedRegistry(libdirs, agentddl.StrictValidatorsFromConfig(p.cfg))
 
-		err := filepath.Walk(agentsdir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-
-			if info.IsDir() {
-				return nil
-			}
-
-			fname := info.Name()
-			extension := filepath.Ext(fname)
-			name := strings.TrimSuffix(fname, extension)
-
-			if extension != ".json" {
-				return nil
-			}
-
-			if !shouldLoadAgent(name) {
-				p.log.Warnf("Ruby agents are not allowed to supply an agent called '%s', skipping", name)
-				return nil
-			}
+	for _, name := range registry.Names() {
+		if !shouldLoadAgent(name) {
+			p.log.Warnf("Ruby agents are not allowed to supply an agent called '%s', skipping", name)
+			continue
+		}
 
-			bpath := strings.TrimSuffix(path, extension)
-			rbfile := bpath + ".rb"
+		ddl, shadowed, err := registry.FindProvider(name, "ruby")
+		if err != nil {
+			p.log.Errorf("Could not load ruby agent DDL for %s: %s", name, err)
+			continue
+		}
 
-			rbstat, err := os.Stat(rbfile)
-			if os.IsNotExist(err) || rbstat.IsDir() {
-				return nil
-			}
+		if ddl == nil {
+			continue
+		}
 
-			p.log.Debugf("Attempting to load %s as an agent DDL", path)
+		rbfile := strings.TrimSuffix(ddl.SourceLocation, filepath.Ext(ddl.SourceLocation)) + ".rb"
 
-			ddl, err := agentddl.New(path)
-			if err != nil {
-				p.log.Errorf("Could not load ruby agent DDL %s: %s", path, err)
-				return nil
-			}
+		rbstat, err := os.Stat(rbfile)
+		if os.IsNotExist(err) || rbstat.IsDir() {
+			continue
+		}
 
-			if ddl.Metadata.Provider == "" || ddl.Metadata.Provider == "ruby" {
-				cb(ddl)
-			}
+		for _, path := range shadowed {
+			p.log.Warnf("Ignoring %s as the %s agent is already provided by %s", path, name, ddl.SourceLocation)
+		}
 
-			return nil
-		})
+		p.log.Debugf("Loaded %s as an agent DDL", ddl.SourceLocation)
 
-		if err != nil {
-			p.log.Errorf("Could not find agents in %s: %s", dir, err)
-		}
+		cb(ddl)
 	}
 }
 